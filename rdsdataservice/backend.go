@@ -0,0 +1,153 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgdataapi"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const (
+	// backendDataAPI runs statements through the RDS Data API HTTP endpoint.
+	backendDataAPI = "data_api"
+	// backendPostgres runs statements over a direct PostgreSQL connection,
+	// for provisioned clusters without the HTTP endpoint and for plain
+	// PostgreSQL.
+	backendPostgres = "postgres"
+)
+
+func postgresSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"dsn": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Sensitive:   true,
+					Description: descriptions["postgres_dsn"],
+				},
+				"host": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: descriptions["postgres_host"],
+				},
+				"port": {
+					Type:        schema.TypeInt,
+					Optional:    true,
+					Default:     5432,
+					Description: descriptions["postgres_port"],
+				},
+				"username": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Description: descriptions["postgres_username"],
+				},
+				"password": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Sensitive:   true,
+					Description: descriptions["postgres_password"],
+				},
+				"database": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     "postgres",
+					Description: descriptions["postgres_database"],
+				},
+				"sslmode": {
+					Type:        schema.TypeString,
+					Optional:    true,
+					Default:     "require",
+					Description: descriptions["postgres_sslmode"],
+				},
+			},
+		},
+	}
+}
+
+// postgresDSN returns the connection string described by a postgres block:
+// its dsn if set, otherwise one built from the individual arguments.
+func postgresDSN(postgres map[string]interface{}) (string, error) {
+	if dsn := postgres["dsn"].(string); dsn != "" {
+		if postgres["host"].(string) != "" || postgres["username"].(string) != "" || postgres["password"].(string) != "" {
+			return "", fmt.Errorf("postgres: dsn cannot be combined with host, username or password")
+		}
+		return dsn, nil
+	}
+
+	if postgres["host"].(string) == "" {
+		return "", fmt.Errorf("postgres: either dsn or host must be set")
+	}
+
+	params := []string{
+		"host=" + pgdataapi.QuoteConninfo(postgres["host"].(string)),
+		fmt.Sprintf("port=%d", postgres["port"].(int)),
+		"dbname=" + pgdataapi.QuoteConninfo(postgres["database"].(string)),
+		"sslmode=" + pgdataapi.QuoteConninfo(postgres["sslmode"].(string)),
+	}
+	if v := postgres["username"].(string); v != "" {
+		params = append(params, "user="+pgdataapi.QuoteConninfo(v))
+	}
+	if v := postgres["password"].(string); v != "" {
+		params = append(params, "password="+pgdataapi.QuoteConninfo(v))
+	}
+
+	return strings.Join(params, " "), nil
+}
+
+// postgresClient returns a client whose resources run their statements over
+// a direct connection. No AWS session is created, so no AWS credentials are
+// needed.
+func (c *Config) postgresClient() (interface{}, error) {
+	log.Printf("[INFO] Using the PostgreSQL backend")
+
+	executor, err := pgdataapi.Open(c.PostgresDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &AWSClient{
+		backend:  backendPostgres,
+		executor: executor,
+		database: c.Database,
+		region:   c.Region,
+	}
+	if err := client.resolveDatabase(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// resolveDatabase sets the client database, when the provider sets none, to
+// the one the connection defaults to. Resources that set no database of
+// their own then check, grant on and identify the same database they run
+// their statements in.
+func (c *AWSClient) resolveDatabase() error {
+	if c.database != "" {
+		return nil
+	}
+
+	rows, err := queryRows(c, dataAPITarget{}, "SELECT current_database() AS database")
+	if err != nil {
+		return fmt.Errorf("Error reading the PostgreSQL connection database: %#v", err)
+	}
+	if len(rows) != 1 || rows[0].getString("database") == "" {
+		return fmt.Errorf("Error reading the PostgreSQL connection database: no database returned")
+	}
+
+	c.database = rows[0].getString("database")
+	log.Printf("[DEBUG] PostgreSQL connection database: %s", c.database)
+	return nil
+}
+
+// requiresDataAPITarget reports whether resources need a cluster and secret
+// ARN to run statements, which only the Data API backend uses.
+func (c *AWSClient) requiresDataAPITarget() bool {
+	return c.backend != backendPostgres
+}
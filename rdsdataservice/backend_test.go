@@ -0,0 +1,171 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func testPostgresBlock(values map[string]interface{}) map[string]interface{} {
+	postgres := map[string]interface{}{
+		"dsn":      "",
+		"host":     "",
+		"port":     5432,
+		"username": "",
+		"password": "",
+		"database": "postgres",
+		"sslmode":  "require",
+	}
+	for k, v := range values {
+		postgres[k] = v
+	}
+	return postgres
+}
+
+func TestPostgresDSN(t *testing.T) {
+	testCases := []struct {
+		name     string
+		postgres map[string]interface{}
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "dsn",
+			postgres: testPostgresBlock(map[string]interface{}{"dsn": "postgres://app@db/app"}),
+			want:     "postgres://app@db/app",
+		},
+		{
+			name: "host",
+			postgres: testPostgresBlock(map[string]interface{}{
+				"host":     "db.example.com",
+				"username": "app",
+				"password": `it's\secret`,
+			}),
+			want: `host='db.example.com' port=5432 dbname='postgres' sslmode='require' user='app' password='it\'s\\secret'`,
+		},
+		{
+			name:     "host without credentials",
+			postgres: testPostgresBlock(map[string]interface{}{"host": "localhost", "sslmode": "disable"}),
+			want:     `host='localhost' port=5432 dbname='postgres' sslmode='disable'`,
+		},
+		{
+			name: "dsn and host",
+			postgres: testPostgresBlock(map[string]interface{}{
+				"dsn":  "postgres://app@db/app",
+				"host": "db",
+			}),
+			wantErr: "dsn cannot be combined",
+		},
+		{
+			name:     "neither",
+			postgres: testPostgresBlock(nil),
+			wantErr:  "either dsn or host must be set",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := postgresDSN(testCase.postgres)
+			if testCase.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", testCase.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if got != testCase.want {
+				t.Errorf("got %q, expected %q", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestPostgresClient(t *testing.T) {
+	config := Config{
+		Backend:     backendPostgres,
+		PostgresDSN: "host=localhost",
+		Database:    "app",
+	}
+
+	raw, err := config.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client := raw.(*AWSClient)
+
+	if client.requiresDataAPITarget() {
+		t.Error("expected the postgres backend not to require a Data API target")
+	}
+	if client.database != "app" {
+		t.Errorf("got database %q", client.database)
+	}
+	if client.rdsdataserviceconn != nil {
+		t.Error("expected no AWS session to be created")
+	}
+}
+
+func TestPostgresClientResolveDatabase(t *testing.T) {
+	testCases := []struct {
+		name     string
+		database string
+		executor *fakeExecutor
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "provider database",
+			database: "app",
+			executor: &fakeExecutor{},
+			want:     "app",
+		},
+		{
+			name: "connection database",
+			executor: (&fakeExecutor{}).onRows("current_database()", []string{"database"},
+				testRecord(testStringField("tenant"))),
+			want: "tenant",
+		},
+		{
+			name:     "connection error",
+			executor: (&fakeExecutor{}).failOn("current_database()", fmt.Errorf("connection refused")),
+			wantErr:  "connection refused",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := &AWSClient{backend: backendPostgres, executor: testCase.executor, database: testCase.database}
+
+			err := client.resolveDatabase()
+			if testCase.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", testCase.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if client.database != testCase.want {
+				t.Errorf("got database %q, expected %q", client.database, testCase.want)
+			}
+			if testCase.database != "" && len(testCase.executor.statements) != 0 {
+				t.Errorf("expected no query, got %v", testCase.executor.statements)
+			}
+		})
+	}
+}
+
+func TestDataAPIClientRequiresRegion(t *testing.T) {
+	config := Config{
+		Backend:     backendDataAPI,
+		ResourceArn: testResourceArn,
+		SecretArn:   testSecretArn,
+	}
+
+	_, err := config.Client()
+	if err == nil || !strings.Contains(err.Error(), "region is required") {
+		t.Fatalf("expected a missing region error, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -173,6 +174,19 @@ type Config struct {
 	SkipMetadataApiCheck    bool
 	S3ForcePathStyle        bool
 
+	// Defaults for resources that do not set their own Data API target
+	ResourceArn string
+	SecretArn   string
+	Database    string
+
+	// How long statements wait for a paused Aurora Serverless cluster
+	ResumeTimeout time.Duration
+
+	// Backend is either backendDataAPI or backendPostgres, which connects
+	// to PostgresDSN directly
+	Backend     string
+	PostgresDSN string
+
 	terraformVersion string
 }
 
@@ -191,6 +205,7 @@ type AWSClient struct {
 	athenaconn                          *athena.Athena
 	autoscalingconn                     *autoscaling.AutoScaling
 	autoscalingplansconn                *autoscalingplans.AutoScalingPlans
+	backend                             string
 	backupconn                          *backup.Backup
 	batchconn                           *batch.Batch
 	budgetconn                          *budgets.Budgets
@@ -213,6 +228,7 @@ type AWSClient struct {
 	costandusagereportconn              *costandusagereportservice.CostandUsageReportService
 	datapipelineconn                    *datapipeline.DataPipeline
 	datasyncconn                        *datasync.DataSync
+	database                            string
 	daxconn                             *dax.DAX
 	devicefarmconn                      *devicefarm.DeviceFarm
 	dlmconn                             *dlm.DLM
@@ -234,6 +250,7 @@ type AWSClient struct {
 	elbv2conn                           *elbv2.ELBV2
 	emrconn                             *emr.EMR
 	esconn                              *elasticsearch.ElasticsearchService
+	executor                            statementExecutor
 	firehoseconn                        *firehose.Firehose
 	fmsconn                             *fms.FMS
 	forecastconn                        *forecastservice.ForecastService
@@ -286,6 +303,7 @@ type AWSClient struct {
 	rdsdataserviceconn                  *rdsdataservice.RDSDataService
 	redshiftconn                        *redshift.Redshift
 	region                              string
+	resourceArn                         string
 	resourcegroupsconn                  *resourcegroups.ResourceGroups
 	route53resolverconn                 *route53resolver.Route53Resolver
 	s3conn                              *s3.S3
@@ -294,6 +312,7 @@ type AWSClient struct {
 	sagemakerconn                       *sagemaker.SageMaker
 	scconn                              *servicecatalog.ServiceCatalog
 	sdconn                              *servicediscovery.ServiceDiscovery
+	secretArn                           string
 	secretsmanagerconn                  *secretsmanager.SecretsManager
 	securityhubconn                     *securityhub.SecurityHub
 	serverlessapplicationrepositoryconn *serverlessapplicationrepository.ServerlessApplicationRepository
@@ -320,6 +339,16 @@ type AWSClient struct {
 
 // Client configures and returns a fully initialized AWSClient
 func (c *Config) Client() (interface{}, error) {
+	if c.Backend == backendPostgres {
+		return c.postgresClient()
+	}
+
+	// The region is optional in the provider schema, as only the Data API
+	// backend needs one
+	if c.Region == "" {
+		return nil, fmt.Errorf("region is required when backend is %q", backendDataAPI)
+	}
+
 	// Get the auth and region. This can fail if keys/regions were not
 	// specified and we're attempting to use the environment.
 	if !c.SkipRegionValidation {
@@ -561,6 +590,21 @@ func (c *Config) Client() (interface{}, error) {
 	client.r53conn = route53.New(sess.Copy(route53Config))
 	client.shieldconn = shield.New(sess.Copy(shieldConfig))
 
+	// All SQL issued by the resources goes through the executor
+	client.backend = backendDataAPI
+	client.executor = &dataAPIExecutor{conn: client.rdsdataserviceconn}
+	client.resourceArn = c.ResourceArn
+	client.secretArn = c.SecretArn
+	client.database = c.Database
+
+	// Statements may contain role passwords and secret values are
+	// passwords, so their bodies are never logged
+	for _, cfg := range []*aws.Config{&client.rdsdataserviceconn.Config, &client.secretsmanagerconn.Config} {
+		if cfg.LogLevel.AtLeast(aws.LogDebug) {
+			cfg.LogLevel = aws.LogLevel(cfg.LogLevel.Value() &^ (aws.LogDebugWithHTTPBody &^ aws.LogDebug))
+		}
+	}
+
 	// Workaround for https://github.com/aws/aws-sdk-go/issues/1472
 	client.appautoscalingconn.Handlers.Retry.PushBack(func(r *request.Request) {
 		if !strings.HasPrefix(r.Operation.Name, "Describe") && !strings.HasPrefix(r.Operation.Name, "List") {
@@ -669,6 +713,16 @@ func (c *Config) Client() (interface{}, error) {
 		}
 	})
 
+	// Auto-paused Aurora Serverless clusters reject statements until they
+	// have resumed, which can take longer than the default retries allow.
+	resumeTimeout := c.ResumeTimeout
+	if resumeTimeout == 0 {
+		resumeTimeout = defaultResumeTimeout
+	}
+	maxRetries := client.rdsdataserviceconn.MaxRetries()
+	client.rdsdataserviceconn.Retryer = newDataAPIRetryer(maxRetries, resumeTimeout)
+	client.rdsdataserviceconn.Handlers.Retry.PushBack(dataAPIRetryHandler(resumeTimeout, maxRetries))
+
 	client.storagegatewayconn.Handlers.Retry.PushBack(func(r *request.Request) {
 		// InvalidGatewayRequestException: The specified gateway proxy network connection is busy.
 		if isAWSErr(r.Error, storagegateway.ErrCodeInvalidGatewayRequestException, "The specified gateway proxy network connection is busy") {
@@ -0,0 +1,103 @@
+package rdsdataservice
+
+import (
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+)
+
+const (
+	// defaultResumeTimeout is how long statements wait for an auto-paused
+	// Aurora Serverless cluster to resume when the provider sets no
+	// resume_timeout.
+	defaultResumeTimeout = 5 * time.Minute
+
+	// dataAPIResumeRetryDelay is the delay between attempts while the
+	// cluster resumes. Resuming takes tens of seconds, so there is no point
+	// in backing off exponentially.
+	dataAPIResumeRetryDelay = 5 * time.Second
+)
+
+// dataAPIResumingMessages are the messages of the BadRequestException
+// returned by the Data API while a paused cluster is resuming.
+var dataAPIResumingMessages = []string{
+	"Communications link failure",
+	"database is resuming",
+}
+
+// isDataAPIResumingErr returns true if err means the cluster is paused or
+// still resuming, and the request will succeed once it is up.
+func isDataAPIResumingErr(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	if !ok || awsErr.Code() != rdsdataservice.ErrCodeBadRequestException {
+		return false
+	}
+	for _, message := range dataAPIResumingMessages {
+		if strings.Contains(awsErr.Message(), message) {
+			return true
+		}
+	}
+	return false
+}
+
+// dataAPIRetryer keeps retrying requests that fail because the cluster is
+// resuming until resumeTimeout has elapsed, at a fixed interval. Any other
+// error is retried like the default retryer does, at most NumMaxRetries
+// times.
+type dataAPIRetryer struct {
+	client.DefaultRetryer
+	resumeTimeout time.Duration
+}
+
+func newDataAPIRetryer(maxRetries int, resumeTimeout time.Duration) request.Retryer {
+	return dataAPIRetryer{
+		DefaultRetryer: client.DefaultRetryer{NumMaxRetries: maxRetries},
+		resumeTimeout:  resumeTimeout,
+	}
+}
+
+// MaxRetries is not a bound for this retryer: resume errors are bounded by
+// resumeTimeout in dataAPIRetryHandler and other errors by ShouldRetry.
+func (d dataAPIRetryer) MaxRetries() int {
+	return math.MaxInt32
+}
+
+func (d dataAPIRetryer) ShouldRetry(r *request.Request) bool {
+	return r.RetryCount < d.NumMaxRetries && d.DefaultRetryer.ShouldRetry(r)
+}
+
+func (d dataAPIRetryer) RetryRules(r *request.Request) time.Duration {
+	if isDataAPIResumingErr(r.Error) {
+		return dataAPIResumeRetryDelay
+	}
+	return d.DefaultRetryer.RetryRules(r)
+}
+
+// dataAPIRetryHandler classifies Data API errors for the Retry handler list.
+// Requests against a resuming cluster are retried until resumeTimeout has
+// elapsed since the request was created; throttling and transient
+// ServiceUnavailableError responses are retried up to maxRetries times.
+func dataAPIRetryHandler(resumeTimeout time.Duration, maxRetries int) func(*request.Request) {
+	return func(r *request.Request) {
+		switch {
+		case isDataAPIResumingErr(r.Error):
+			elapsed := time.Since(r.Time)
+			if elapsed >= resumeTimeout {
+				log.Printf("[WARN] Aurora Serverless cluster did not resume within resume_timeout (%s)", resumeTimeout)
+				r.Retryable = aws.Bool(false)
+				return
+			}
+			log.Printf("[INFO] Waiting for Aurora Serverless cluster to resume (%s of %s elapsed)", elapsed.Round(time.Second), resumeTimeout)
+			r.Retryable = aws.Bool(true)
+		case isAWSErr(r.Error, rdsdataservice.ErrCodeServiceUnavailableError, ""), request.IsErrorThrottle(r.Error):
+			r.Retryable = aws.Bool(r.RetryCount < maxRetries)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package rdsdataservice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+)
+
+var (
+	testResumingErr = awserr.New(rdsdataservice.ErrCodeBadRequestException,
+		"Communications link failure\n\nThe last packet sent successfully to the server was 0 milliseconds ago.", nil)
+	testSyntaxErr = awserr.New(rdsdataservice.ErrCodeBadRequestException,
+		`ERROR: syntax error at or near "ROLE"`, nil)
+	testUnavailableErr = awserr.New(rdsdataservice.ErrCodeServiceUnavailableError, "", nil)
+	testThrottlingErr  = awserr.New("ThrottlingException", "Rate exceeded", nil)
+)
+
+func TestIsDataAPIResumingErr(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name: "nil error",
+		},
+		{
+			name: "other error",
+			err:  errors.New("Communications link failure"),
+		},
+		{
+			name:     "communications link failure",
+			err:      testResumingErr,
+			expected: true,
+		},
+		{
+			name:     "database is resuming",
+			err:      awserr.New(rdsdataservice.ErrCodeBadRequestException, "The database is resuming. Please try again.", nil),
+			expected: true,
+		},
+		{
+			name: "syntax error",
+			err:  testSyntaxErr,
+		},
+		{
+			name: "other code",
+			err:  awserr.New(rdsdataservice.ErrCodeForbiddenException, "Communications link failure", nil),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := isDataAPIResumingErr(testCase.err); got != testCase.expected {
+				t.Errorf("got %t, expected %t", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestDataAPIRetryHandler(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		age        time.Duration
+		retryCount int
+		expected   *bool
+	}{
+		{
+			name:     "resuming",
+			err:      testResumingErr,
+			age:      time.Minute,
+			expected: aws.Bool(true),
+		},
+		{
+			name:       "resuming beyond max retries",
+			err:        testResumingErr,
+			age:        time.Minute,
+			retryCount: 100,
+			expected:   aws.Bool(true),
+		},
+		{
+			name:     "resume timeout elapsed",
+			err:      testResumingErr,
+			age:      10 * time.Minute,
+			expected: aws.Bool(false),
+		},
+		{
+			name:     "service unavailable",
+			err:      testUnavailableErr,
+			expected: aws.Bool(true),
+		},
+		{
+			name:       "service unavailable beyond max retries",
+			err:        testUnavailableErr,
+			retryCount: 3,
+			expected:   aws.Bool(false),
+		},
+		{
+			name:     "throttling",
+			err:      testThrottlingErr,
+			expected: aws.Bool(true),
+		},
+		{
+			name: "syntax error left to the retryer",
+			err:  testSyntaxErr,
+		},
+	}
+
+	handler := dataAPIRetryHandler(5*time.Minute, 3)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := &request.Request{
+				Error:      testCase.err,
+				Time:       time.Now().Add(-testCase.age),
+				RetryCount: testCase.retryCount,
+			}
+
+			handler(r)
+
+			if testCase.expected == nil {
+				if r.Retryable != nil {
+					t.Fatalf("got %t, expected the error to be left unclassified", *r.Retryable)
+				}
+				return
+			}
+			if r.Retryable == nil || *r.Retryable != *testCase.expected {
+				t.Fatalf("got %v, expected %t", r.Retryable, *testCase.expected)
+			}
+		})
+	}
+}
+
+func TestDataAPIRetryer(t *testing.T) {
+	retryer := newDataAPIRetryer(3, 5*time.Minute)
+
+	if got := retryer.RetryRules(&request.Request{Error: testResumingErr}); got != dataAPIResumeRetryDelay {
+		t.Errorf("resume delay: got %s, expected %s", got, dataAPIResumeRetryDelay)
+	}
+
+	unavailable := &request.Request{Error: testUnavailableErr}
+	if retryer.ShouldRetry(unavailable) != retryer.(dataAPIRetryer).DefaultRetryer.ShouldRetry(unavailable) {
+		t.Errorf("expected the default retry classification below max retries")
+	}
+
+	unavailable.RetryCount = 3
+	if retryer.ShouldRetry(unavailable) {
+		t.Errorf("expected no retry once max retries is reached")
+	}
+}
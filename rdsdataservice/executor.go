@@ -0,0 +1,155 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+)
+
+// statementExecutor is the subset of the RDS Data API used by the resources
+// in this provider. AWSClient exposes one through its executor field so that
+// the CRUD functions never talk to the SDK client directly and can be unit
+// tested against an in-memory implementation.
+type statementExecutor interface {
+	ExecuteStatement(*rdsdataservice.ExecuteStatementInput) (*rdsdataservice.ExecuteStatementOutput, error)
+	BatchExecuteStatement(*rdsdataservice.BatchExecuteStatementInput) (*rdsdataservice.BatchExecuteStatementOutput, error)
+	BeginTransaction(*rdsdataservice.BeginTransactionInput) (*rdsdataservice.BeginTransactionOutput, error)
+	CommitTransaction(*rdsdataservice.CommitTransactionInput) (*rdsdataservice.CommitTransactionOutput, error)
+	RollbackTransaction(*rdsdataservice.RollbackTransactionInput) (*rdsdataservice.RollbackTransactionOutput, error)
+}
+
+// dataAPIExecutor runs statements through the RDS Data API HTTP endpoint.
+type dataAPIExecutor struct {
+	conn *rdsdataservice.RDSDataService
+}
+
+var _ statementExecutor = &dataAPIExecutor{}
+
+func (e *dataAPIExecutor) ExecuteStatement(input *rdsdataservice.ExecuteStatementInput) (*rdsdataservice.ExecuteStatementOutput, error) {
+	return e.conn.ExecuteStatement(input)
+}
+
+func (e *dataAPIExecutor) BatchExecuteStatement(input *rdsdataservice.BatchExecuteStatementInput) (*rdsdataservice.BatchExecuteStatementOutput, error) {
+	return e.conn.BatchExecuteStatement(input)
+}
+
+func (e *dataAPIExecutor) BeginTransaction(input *rdsdataservice.BeginTransactionInput) (*rdsdataservice.BeginTransactionOutput, error) {
+	return e.conn.BeginTransaction(input)
+}
+
+func (e *dataAPIExecutor) CommitTransaction(input *rdsdataservice.CommitTransactionInput) (*rdsdataservice.CommitTransactionOutput, error) {
+	return e.conn.CommitTransaction(input)
+}
+
+func (e *dataAPIExecutor) RollbackTransaction(input *rdsdataservice.RollbackTransactionInput) (*rdsdataservice.RollbackTransactionOutput, error) {
+	return e.conn.RollbackTransaction(input)
+}
+
+// withTransaction runs f inside a Data API transaction on target. The
+// transaction is committed when f succeeds and rolled back when it returns an
+// error, so multi-statement operations are never left half-applied. Every
+// statement issued by f must set TransactionId to the ID it is given.
+func withTransaction(executor statementExecutor, target dataAPITarget, f func(transactionID *string) error) error {
+	beginOpts := rdsdataservice.BeginTransactionInput{
+		ResourceArn: aws.String(target.ResourceArn),
+		SecretArn:   aws.String(target.SecretArn),
+	}
+	if target.Database != "" {
+		beginOpts.Database = aws.String(target.Database)
+	}
+
+	log.Printf("[DEBUG] Begin transaction: %#v", beginOpts)
+
+	output, err := executor.BeginTransaction(&beginOpts)
+
+	if err != nil {
+		return fmt.Errorf("Error beginning transaction: %#v", err)
+	}
+
+	transactionID := output.TransactionId
+
+	if err := f(transactionID); err != nil {
+		log.Printf("[DEBUG] Rolling back transaction: %s", aws.StringValue(transactionID))
+
+		_, rollbackErr := executor.RollbackTransaction(&rdsdataservice.RollbackTransactionInput{
+			ResourceArn:   aws.String(target.ResourceArn),
+			SecretArn:     aws.String(target.SecretArn),
+			TransactionId: transactionID,
+		})
+
+		if rollbackErr != nil {
+			log.Printf("[WARN] Error rolling back transaction %s: %#v", aws.StringValue(transactionID), rollbackErr)
+		}
+
+		return err
+	}
+
+	log.Printf("[DEBUG] Commit transaction: %s", aws.StringValue(transactionID))
+
+	_, err = executor.CommitTransaction(&rdsdataservice.CommitTransactionInput{
+		ResourceArn:   aws.String(target.ResourceArn),
+		SecretArn:     aws.String(target.SecretArn),
+		TransactionId: transactionID,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error committing transaction: %#v", err)
+	}
+
+	return nil
+}
+
+// executeDDL runs input with ContinueAfterTimeout set, so that statements
+// taking longer than the Data API call timeout keep running on the cluster.
+// When the call times out, done is polled until it reports that the
+// statement has completed, or until timeout has elapsed since the call was
+// made. A nil done turns the call timeout into an error.
+func executeDDL(executor statementExecutor, input *rdsdataservice.ExecuteStatementInput, timeout time.Duration, done func() (bool, error)) error {
+	start := time.Now()
+	input.ContinueAfterTimeout = aws.Bool(true)
+
+	_, err := executor.ExecuteStatement(input)
+
+	if !isAWSErr(err, rdsdataservice.ErrCodeStatementTimeoutException, "") || done == nil {
+		return err
+	}
+
+	log.Printf("[INFO] Statement still running after the Data API call timed out, waiting up to %s for it to complete", timeout)
+
+	return resource.Retry(timeout-time.Since(start), func() *resource.RetryError {
+		completed, err := done()
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+		if !completed {
+			return resource.RetryableError(fmt.Errorf("Statement has not completed yet: %s", aws.StringValue(input.Sql)))
+		}
+		return nil
+	})
+}
+
+// statementCompleted returns a done function for executeDDL that waits for
+// the statements already sent in a transaction. The statements of a
+// transaction run one after another on the same connection, so a query in
+// the same transaction only returns once the statement before it is done;
+// if that statement failed, the query fails with it.
+func statementCompleted(executor statementExecutor, target dataAPITarget, transactionID *string) func() (bool, error) {
+	return func() (bool, error) {
+		queryOpts := target.statement("SELECT 1")
+		queryOpts.TransactionId = transactionID
+
+		_, err := executor.ExecuteStatement(&queryOpts)
+
+		if isAWSErr(err, rdsdataservice.ErrCodeStatementTimeoutException, "") {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+}
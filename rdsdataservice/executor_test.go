@@ -0,0 +1,314 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+const (
+	testResourceArn = "arn:aws:rds:us-east-1:123456789012:cluster:test"
+	testSecretArn   = "arn:aws:secretsmanager:us-east-1:123456789012:secret:test"
+)
+
+// fakeExecutor is an in-memory statementExecutor. It records every statement
+// it is asked to run and answers with the canned records of the first fixture
+// whose match string is contained in the SQL.
+type fakeExecutor struct {
+	statements   []string
+	inputs       []*rdsdataservice.ExecuteStatementInput
+	fixtures     []*fakeFixture
+	transactions int
+}
+
+type fakeFixture struct {
+	match   string
+	columns []string
+	records [][]*rdsdataservice.Field
+	err     error
+	once    bool
+	used    bool
+}
+
+var _ statementExecutor = &fakeExecutor{}
+
+// on registers records to return for statements containing match.
+func (f *fakeExecutor) on(match string, records ...[]*rdsdataservice.Field) *fakeExecutor {
+	f.fixtures = append(f.fixtures, &fakeFixture{match: match, records: records})
+	return f
+}
+
+// onRows registers records with the given column names to return for
+// statements containing match. Like the Data API, the column metadata is
+// only returned when the statement asks for it.
+func (f *fakeExecutor) onRows(match string, columns []string, records ...[]*rdsdataservice.Field) *fakeExecutor {
+	f.fixtures = append(f.fixtures, &fakeFixture{match: match, columns: columns, records: records})
+	return f
+}
+
+// onceOn registers records to return for the first statement containing
+// match only.
+func (f *fakeExecutor) onceOn(match string, records ...[]*rdsdataservice.Field) *fakeExecutor {
+	f.fixtures = append(f.fixtures, &fakeFixture{match: match, records: records, once: true})
+	return f
+}
+
+// failOn makes statements containing match fail with err.
+func (f *fakeExecutor) failOn(match string, err error) *fakeExecutor {
+	f.fixtures = append(f.fixtures, &fakeFixture{match: match, err: err})
+	return f
+}
+
+// failOnceOn makes the first statement containing match fail with err.
+func (f *fakeExecutor) failOnceOn(match string, err error) *fakeExecutor {
+	f.fixtures = append(f.fixtures, &fakeFixture{match: match, err: err, once: true})
+	return f
+}
+
+func (f *fakeExecutor) fixture(sql string) *fakeFixture {
+	for _, fixture := range f.fixtures {
+		if fixture.once && fixture.used {
+			continue
+		}
+		if strings.Contains(sql, fixture.match) {
+			fixture.used = true
+			return fixture
+		}
+	}
+	return nil
+}
+
+func (f *fakeExecutor) ExecuteStatement(input *rdsdataservice.ExecuteStatementInput) (*rdsdataservice.ExecuteStatementOutput, error) {
+	sql := normalizeTestSQL(aws.StringValue(input.Sql))
+	f.statements = append(f.statements, sql)
+	f.inputs = append(f.inputs, input)
+
+	output := &rdsdataservice.ExecuteStatementOutput{}
+	if fixture := f.fixture(sql); fixture != nil {
+		if fixture.err != nil {
+			return nil, fixture.err
+		}
+		output.Records = fixture.records
+		if aws.BoolValue(input.IncludeResultMetadata) {
+			for _, column := range fixture.columns {
+				output.ColumnMetadata = append(output.ColumnMetadata, &rdsdataservice.ColumnMetadata{Name: aws.String(column)})
+			}
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeExecutor) BatchExecuteStatement(input *rdsdataservice.BatchExecuteStatementInput) (*rdsdataservice.BatchExecuteStatementOutput, error) {
+	sql := normalizeTestSQL(aws.StringValue(input.Sql))
+	f.statements = append(f.statements, sql)
+
+	if fixture := f.fixture(sql); fixture != nil && fixture.err != nil {
+		return nil, fixture.err
+	}
+	return &rdsdataservice.BatchExecuteStatementOutput{}, nil
+}
+
+func (f *fakeExecutor) BeginTransaction(input *rdsdataservice.BeginTransactionInput) (*rdsdataservice.BeginTransactionOutput, error) {
+	f.transactions++
+	f.statements = append(f.statements, "BEGIN")
+	return &rdsdataservice.BeginTransactionOutput{
+		TransactionId: aws.String(fmt.Sprintf("tx-%d", f.transactions)),
+	}, nil
+}
+
+func (f *fakeExecutor) CommitTransaction(input *rdsdataservice.CommitTransactionInput) (*rdsdataservice.CommitTransactionOutput, error) {
+	f.statements = append(f.statements, "COMMIT")
+	return &rdsdataservice.CommitTransactionOutput{TransactionStatus: aws.String("Transaction Committed")}, nil
+}
+
+func (f *fakeExecutor) RollbackTransaction(input *rdsdataservice.RollbackTransactionInput) (*rdsdataservice.RollbackTransactionOutput, error) {
+	f.statements = append(f.statements, "ROLLBACK")
+	return &rdsdataservice.RollbackTransactionOutput{TransactionStatus: aws.String("Rollback Complete")}, nil
+}
+
+// expectStatements fails the test unless exactly the given statements were
+// run, in order.
+func (f *fakeExecutor) expectStatements(t *testing.T, want ...string) {
+	t.Helper()
+
+	for i := range want {
+		want[i] = normalizeTestSQL(want[i])
+	}
+	if len(want) == 0 && len(f.statements) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(f.statements, want) {
+		t.Fatalf("unexpected statements\ngot:\n  %s\nwant:\n  %s",
+			strings.Join(f.statements, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+// expectParameters fails the test unless the i-th ExecuteStatement call bound
+// exactly the given string parameters.
+func (f *fakeExecutor) expectParameters(t *testing.T, i int, want map[string]string) {
+	t.Helper()
+
+	got := make(map[string]string)
+	for _, parameter := range f.inputs[i].Parameters {
+		got[aws.StringValue(parameter.Name)] = aws.StringValue(parameter.Value.StringValue)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected parameters for %q\ngot:  %v\nwant: %v", aws.StringValue(f.inputs[i].Sql), got, want)
+	}
+}
+
+// normalizeTestSQL collapses whitespace so golden statements can be written
+// without caring about the indentation of multi-line queries.
+func normalizeTestSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+func testStringField(v string) *rdsdataservice.Field {
+	return &rdsdataservice.Field{StringValue: aws.String(v)}
+}
+
+func testBoolField(v bool) *rdsdataservice.Field {
+	return &rdsdataservice.Field{BooleanValue: aws.Bool(v)}
+}
+
+func testLongField(v int64) *rdsdataservice.Field {
+	return &rdsdataservice.Field{LongValue: aws.Int64(v)}
+}
+
+func testRecord(fields ...*rdsdataservice.Field) []*rdsdataservice.Field {
+	return fields
+}
+
+// testResourceDataUpdate returns the ResourceData an Update function would
+// receive when changing a resource with the given ID from the old to the new
+// configuration.
+func testResourceDataUpdate(t *testing.T, r *schema.Resource, id string, old, new map[string]interface{}, meta interface{}) *schema.ResourceData {
+	t.Helper()
+
+	state := schema.TestResourceDataRaw(t, r.Schema, old)
+	state.SetId(id)
+
+	diff, err := r.Diff(state.State(), terraform.NewResourceConfigRaw(new), meta)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	d, err := schema.InternalMap(r.Schema).Data(state.State(), diff)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return d
+}
+
+func TestFakeExecutorFixtures(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		on("FROM pg_roles", testRecord(testStringField("app"))).
+		failOn("DROP", fmt.Errorf("boom"))
+
+	output, err := executor.ExecuteStatement(&rdsdataservice.ExecuteStatementInput{
+		Sql: aws.String("SELECT rolname\n  FROM pg_roles"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(output.Records) != 1 || aws.StringValue(output.Records[0][0].StringValue) != "app" {
+		t.Fatalf("unexpected records: %#v", output.Records)
+	}
+
+	if _, err := executor.ExecuteStatement(&rdsdataservice.ExecuteStatementInput{Sql: aws.String("DROP ROLE app")}); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	executor.expectStatements(t, "SELECT rolname FROM pg_roles", "DROP ROLE app")
+}
+
+func TestExecuteDDL(t *testing.T) {
+	timeoutErr := awserr.New(rdsdataservice.ErrCodeStatementTimeoutException, "Request timed out", nil)
+
+	t.Run("completed", func(t *testing.T) {
+		executor := &fakeExecutor{}
+		input := &rdsdataservice.ExecuteStatementInput{Sql: aws.String("CREATE DATABASE app")}
+
+		if err := executeDDL(executor, input, time.Minute, nil); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if !aws.BoolValue(input.ContinueAfterTimeout) {
+			t.Fatal("expected ContinueAfterTimeout to be set")
+		}
+	})
+
+	t.Run("polls after a call timeout", func(t *testing.T) {
+		executor := (&fakeExecutor{}).failOnceOn("CREATE DATABASE", timeoutErr)
+		polls := 0
+		done := func() (bool, error) {
+			polls++
+			return polls == 2, nil
+		}
+
+		if err := executeDDL(executor, &rdsdataservice.ExecuteStatementInput{Sql: aws.String("CREATE DATABASE app")}, time.Minute, done); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if polls != 2 {
+			t.Fatalf("expected 2 polls, got %d", polls)
+		}
+	})
+
+	t.Run("no end state", func(t *testing.T) {
+		executor := (&fakeExecutor{}).failOnceOn("CREATE DATABASE", timeoutErr)
+
+		err := executeDDL(executor, &rdsdataservice.ExecuteStatementInput{Sql: aws.String("CREATE DATABASE app")}, time.Minute, nil)
+		if !isAWSErr(err, rdsdataservice.ErrCodeStatementTimeoutException, "") {
+			t.Fatalf("expected the call timeout, got %v", err)
+		}
+	})
+
+	t.Run("poll error", func(t *testing.T) {
+		executor := (&fakeExecutor{}).failOnceOn("CREATE DATABASE", timeoutErr)
+		done := func() (bool, error) {
+			return false, fmt.Errorf("boom")
+		}
+
+		err := executeDDL(executor, &rdsdataservice.ExecuteStatementInput{Sql: aws.String("CREATE DATABASE app")}, time.Minute, done)
+		if err == nil || !strings.Contains(err.Error(), "boom") {
+			t.Fatalf("expected the poll error, got %v", err)
+		}
+	})
+
+	t.Run("terraform timeout", func(t *testing.T) {
+		executor := (&fakeExecutor{}).failOnceOn("CREATE DATABASE", timeoutErr)
+		done := func() (bool, error) {
+			return false, nil
+		}
+
+		err := executeDDL(executor, &rdsdataservice.ExecuteStatementInput{Sql: aws.String("CREATE DATABASE app")}, 50*time.Millisecond, done)
+		if err == nil || !strings.Contains(err.Error(), "has not completed yet") {
+			t.Fatalf("expected a timeout, got %v", err)
+		}
+	})
+}
+
+func TestStatementCompleted(t *testing.T) {
+	timeoutErr := awserr.New(rdsdataservice.ErrCodeStatementTimeoutException, "Request timed out", nil)
+	executor := (&fakeExecutor{}).
+		failOnceOn("SELECT 1", timeoutErr).
+		failOn("SELECT 1", fmt.Errorf("current transaction is aborted"))
+	done := statementCompleted(executor, dataAPITarget{ResourceArn: testResourceArn, SecretArn: testSecretArn}, aws.String("tx-1"))
+
+	if completed, err := done(); completed || err != nil {
+		t.Fatalf("expected a running statement, got %t, %v", completed, err)
+	}
+	if aws.StringValue(executor.inputs[0].TransactionId) != "tx-1" {
+		t.Fatalf("query ran outside the transaction")
+	}
+	if _, err := done(); err == nil {
+		t.Fatal("expected the failure of the statement")
+	}
+}
@@ -3,6 +3,7 @@ package rdsdataservice
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rdsdataservice"
@@ -10,20 +11,42 @@ import (
 	"github.com/lib/pq"
 )
 
-func dbExists(dbname string, d *schema.ResourceData, meta interface{}) (bool, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+// queryCatalog runs a read-only query against target with the given values
+// bound as named Data API parameters. Placeholders in sql use the ":name"
+// syntax; values are never interpolated into the statement text.
+func queryCatalog(meta interface{}, target dataAPITarget, sql string, parameters ...*rdsdataservice.SqlParameter) (*rdsdataservice.ExecuteStatementOutput, error) {
+	executor := meta.(*AWSClient).executor
 
-	sql := fmt.Sprintf("SELECT datname FROM pg_database WHERE datname='%s'", dbname)
+	queryOpts := target.statement(sql)
+	queryOpts.Parameters = parameters
+	queryOpts.IncludeResultMetadata = aws.Bool(true)
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	log.Printf("[DEBUG] Query catalog: %#v", queryOpts)
+
+	return executor.ExecuteStatement(&queryOpts)
+}
+
+// stringParameter returns a named string parameter for queryCatalog.
+func stringParameter(name, value string) *rdsdataservice.SqlParameter {
+	return &rdsdataservice.SqlParameter{
+		Name:  aws.String(name),
+		Value: &rdsdataservice.Field{StringValue: aws.String(value)},
 	}
+}
 
-	log.Printf("[DEBUG] Check db exists: %#v", createOpts)
+// databaseObjectID returns the ID of an object name in database, which is
+// name alone when no database is known.
+func databaseObjectID(database, name string) string {
+	if database == "" {
+		return name
+	}
+	return database + "." + name
+}
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+func dbExists(dbname string, target dataAPITarget, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, target,
+		"SELECT datname FROM pg_database WHERE datname = :name",
+		stringParameter("name", dbname))
 
 	if err != nil {
 		return false, fmt.Errorf("Error checking db exists: %#v", err)
@@ -36,23 +59,29 @@ func dbExists(dbname string, d *schema.ResourceData, meta interface{}) (bool, er
 	return true, nil
 }
 
-func schemaExists(schemaname string, d *schema.ResourceData, meta interface{}) (bool, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+func schemaExists(schemaname string, target dataAPITarget, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, target,
+		"SELECT 1 FROM pg_namespace WHERE nspname = :name",
+		stringParameter("name", schemaname))
 
-	sql := fmt.Sprintf("SELECT 1 FROM pg_namespace WHERE nspname='%s'", schemaname)
+	if err != nil {
+		return false, fmt.Errorf("Error checking schema exists: %#v", err)
+	}
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	if len(output.Records) == 0 {
+		return false, nil
 	}
 
-	log.Printf("[DEBUG] Check schema exists: %#v", createOpts)
+	return true, nil
+}
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+func roleExists(rolename string, target dataAPITarget, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, target,
+		"SELECT 1 FROM pg_roles WHERE rolname = :name",
+		stringParameter("name", rolename))
 
 	if err != nil {
-		return false, fmt.Errorf("Error checking schema exists: %#v", err)
+		return false, fmt.Errorf("Error checking role exists: %#v", err)
 	}
 
 	if len(output.Records) == 0 {
@@ -62,30 +91,40 @@ func schemaExists(schemaname string, d *schema.ResourceData, meta interface{}) (
 	return true, nil
 }
 
-func roleExists(rolename string, d *schema.ResourceData, meta interface{}) (bool, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+func dbOwnedBy(dbname, owner string, target dataAPITarget, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, target,
+		"SELECT 1 FROM pg_database WHERE datname = :name AND pg_catalog.pg_get_userbyid(datdba) = :owner",
+		stringParameter("name", dbname),
+		stringParameter("owner", owner))
 
-	sql := fmt.Sprintf("SELECT 1 FROM pg_roles WHERE rolname='%s'", rolename)
-
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	if err != nil {
+		return false, fmt.Errorf("Error checking db owner: %#v", err)
 	}
 
-	log.Printf("[DEBUG] Check role exists: %#v", createOpts)
+	return len(output.Records) > 0, nil
+}
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+func schemaOwnedBy(schemaname, owner string, target dataAPITarget, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, target,
+		"SELECT 1 FROM pg_namespace WHERE nspname = :name AND pg_catalog.pg_get_userbyid(nspowner) = :owner",
+		stringParameter("name", schemaname),
+		stringParameter("owner", owner))
 
 	if err != nil {
-		return false, fmt.Errorf("Error checking role exists: %#v", err)
+		return false, fmt.Errorf("Error checking schema owner: %#v", err)
 	}
 
-	if len(output.Records) == 0 {
-		return false, nil
-	}
+	return len(output.Records) > 0, nil
+}
 
-	return true, nil
+// stringInSlice returns true if list contains v.
+func stringInSlice(v string, list []string) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+	return false
 }
 
 func pgArrayToSet(arr pq.ByteaArray) *schema.Set {
@@ -95,3 +134,106 @@ func pgArrayToSet(arr pq.ByteaArray) *schema.Set {
 	}
 	return schema.NewSet(schema.HashString, s)
 }
+
+// roleMembership returns whether role is a direct member of grantRole, and
+// whether it may grant that membership to others.
+func roleMembership(role, grantRole string, target dataAPITarget, meta interface{}) (bool, bool, error) {
+	rows, err := queryRows(meta, target, `
+SELECT pg_auth_members.admin_option
+FROM pg_auth_members
+JOIN pg_roles granted ON granted.oid = pg_auth_members.roleid
+JOIN pg_roles member ON member.oid = pg_auth_members.member
+WHERE granted.rolname = :grant_role AND member.rolname = :role`,
+		stringParameter("role", role),
+		stringParameter("grant_role", grantRole))
+
+	if err != nil {
+		return false, false, fmt.Errorf("Error checking role membership: %#v", err)
+	}
+
+	if len(rows) == 0 {
+		return false, false, nil
+	}
+
+	return true, rows[0].getBool("admin_option"), nil
+}
+
+// roleMemberOf returns true if role is a member of grantRole, directly or
+// through other roles.
+func roleMemberOf(role, grantRole string, target dataAPITarget, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, target, `
+WITH RECURSIVE memberships(roleid) AS (
+    SELECT pg_auth_members.roleid
+    FROM pg_auth_members
+    JOIN pg_roles ON pg_roles.oid = pg_auth_members.member
+    WHERE pg_roles.rolname = :role
+  UNION
+    SELECT pg_auth_members.roleid
+    FROM pg_auth_members
+    JOIN memberships ON memberships.roleid = pg_auth_members.member
+)
+SELECT 1
+FROM memberships
+JOIN pg_roles ON pg_roles.oid = memberships.roleid
+WHERE pg_roles.rolname = :grant_role`,
+		stringParameter("role", role),
+		stringParameter("grant_role", grantRole))
+
+	if err != nil {
+		return false, fmt.Errorf("Error checking role membership: %#v", err)
+	}
+
+	return len(output.Records) > 0, nil
+}
+
+// currentUser returns the name of the role the statements on target run as.
+func currentUser(target dataAPITarget, meta interface{}) (string, error) {
+	rows, err := queryRows(meta, target, "SELECT current_user AS name")
+	if err != nil {
+		return "", fmt.Errorf("Error reading current user: %#v", err)
+	}
+
+	if len(rows) != 1 {
+		return "", fmt.Errorf("Error reading current user: no result")
+	}
+
+	return rows[0].getString("name"), nil
+}
+
+// serverVersionNum returns the server_version_num of target, e.g. 130004 for
+// PostgreSQL 13.4.
+func serverVersionNum(target dataAPITarget, meta interface{}) (int64, error) {
+	rows, err := queryRows(meta, target, "SELECT current_setting('server_version_num')::integer AS version")
+	if err != nil {
+		return 0, fmt.Errorf("Error reading server version: %#v", err)
+	}
+
+	if len(rows) != 1 {
+		return 0, fmt.Errorf("Error reading server version: no result")
+	}
+
+	return rows[0].getInt64("version"), nil
+}
+
+// validateNoNUL rejects a string, or a map with a key or value, containing
+// a NUL byte. PostgreSQL cannot store one, so the name or value could only
+// reach it truncated, and act on a different object than configured.
+func validateNoNUL(v interface{}, k string) (ws []string, errors []error) {
+	values := []string{}
+	switch v := v.(type) {
+	case string:
+		values = append(values, v)
+	case map[string]interface{}:
+		for key, value := range v {
+			values = append(values, key, fmt.Sprint(value))
+		}
+	}
+
+	for _, value := range values {
+		if strings.IndexByte(value, 0) > -1 {
+			errors = append(errors, fmt.Errorf("%s cannot contain a NUL byte, got %q", k, value))
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,120 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// dataAPIImport describes the import ID of a resource, of the form
+//
+//	<resource_arn>|<database>|<name>[|<secret_arn>]
+//
+// An empty resource_arn or database part, or a missing secret_arn part,
+// falls back to the provider default, or under the postgres backend to the
+// connection. Resources whose name is made of several attributes, like
+// grants, take one part per attribute.
+type dataAPIImport struct {
+	// resourceType is used in error messages.
+	resourceType string
+	// database is false for cluster-wide objects, which have no database
+	// attribute; their database part must be left empty.
+	database bool
+	// names are the attributes set from the name parts, in order.
+	names []string
+	// optional are the names that may be left empty.
+	optional []string
+	// id returns the resource ID once the attributes are set. The name is
+	// used when it is nil.
+	id func(d *schema.ResourceData, target dataAPITarget) string
+}
+
+func (i dataAPIImport) format() string {
+	database := "<database>"
+	if !i.database {
+		database = ""
+	}
+	names := make([]string, len(i.names))
+	for n, name := range i.names {
+		names[n] = "<" + name + ">"
+	}
+	return fmt.Sprintf("<resource_arn>|%s|%s[|<secret_arn>]", database, strings.Join(names, "|"))
+}
+
+func (i dataAPIImport) importer() *schema.ResourceImporter {
+	return &schema.ResourceImporter{
+		State: i.state,
+	}
+}
+
+func (i dataAPIImport) state(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*AWSClient)
+
+	parts := strings.Split(d.Id(), "|")
+	namesEnd := 2 + len(i.names)
+	if len(parts) != namesEnd && len(parts) != namesEnd+1 {
+		return nil, fmt.Errorf("Unexpected format of %s import ID (%q), expected %s. Names containing \"|\" cannot be imported", i.resourceType, d.Id(), i.format())
+	}
+
+	resourceArn, database, names := parts[0], parts[1], parts[2:namesEnd]
+	secretArn := ""
+	if len(parts) > namesEnd {
+		secretArn = parts[namesEnd]
+	}
+
+	// The postgres backend connects to one server and ignores the ARNs
+	required := client.requiresDataAPITarget()
+
+	if required && resourceArn == "" && client.resourceArn == "" {
+		return nil, fmt.Errorf("Error importing %s %q: the ID has no resource_arn and the provider sets none, expected %s", i.resourceType, d.Id(), i.format())
+	}
+	if resourceArn != "" && !strings.HasPrefix(resourceArn, "arn:") {
+		return nil, fmt.Errorf("Error importing %s %q: resource_arn %q is not an ARN, expected %s", i.resourceType, d.Id(), resourceArn, i.format())
+	}
+	if required && secretArn == "" && client.secretArn == "" {
+		return nil, fmt.Errorf("Error importing %s %q: the ID has no secret_arn and the provider sets none, expected %s", i.resourceType, d.Id(), i.format())
+	}
+	if secretArn != "" && !strings.HasPrefix(secretArn, "arn:") {
+		return nil, fmt.Errorf("Error importing %s %q: secret_arn %q is not an ARN, expected %s", i.resourceType, d.Id(), secretArn, i.format())
+	}
+	if required && i.database && database == "" && client.database == "" {
+		return nil, fmt.Errorf("Error importing %s %q: the ID has no database and the provider sets none, expected %s", i.resourceType, d.Id(), i.format())
+	}
+	if !i.database && database != "" {
+		return nil, fmt.Errorf("Error importing %s %q: %s is not in a database, leave the database part empty: %s", i.resourceType, d.Id(), i.resourceType, i.format())
+	}
+	for n, name := range names {
+		if name == "" && !stringInSlice(i.names[n], i.optional) {
+			return nil, fmt.Errorf("Error importing %s %q: %s is empty, expected %s", i.resourceType, d.Id(), i.names[n], i.format())
+		}
+	}
+
+	// Values equal to the provider defaults are left out of the state, so
+	// that configurations relying on the defaults show no difference.
+	if resourceArn != client.resourceArn {
+		d.Set("resource_arn", resourceArn)
+	}
+	if secretArn != client.secretArn {
+		d.Set("secret_arn", secretArn)
+	}
+	if i.database && database != client.database {
+		d.Set("database", database)
+	}
+	for n, name := range names {
+		d.Set(i.names[n], name)
+	}
+
+	target := resourceTarget(d, meta)
+	if i.database {
+		target = target.withDatabase(d.Get("database").(string))
+	}
+
+	if i.id != nil {
+		d.SetId(i.id(d, target))
+	} else {
+		d.SetId(names[0])
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
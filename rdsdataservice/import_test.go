@@ -0,0 +1,285 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func testImportState(t *testing.T, r *schema.Resource, id string, client *AWSClient) (*schema.ResourceData, error) {
+	t.Helper()
+
+	d := r.Data(nil)
+	d.SetId(id)
+
+	results, err := r.Importer.State(d, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 imported resource, got %d", len(results))
+	}
+	return results[0], nil
+}
+
+func TestDataAPIImport(t *testing.T) {
+	testCases := []struct {
+		name       string
+		resource   *schema.Resource
+		id         string
+		client     *AWSClient
+		expectedID string
+		expected   map[string]string
+	}{
+		{
+			name:       "database",
+			resource:   resourceAwsRdsdataservicePostgresDatabase(),
+			id:         testResourceArn + "||app|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "app",
+			expected: map[string]string{
+				"name":         "app",
+				"resource_arn": testResourceArn,
+				"secret_arn":   testSecretArn,
+			},
+		},
+		{
+			name:       "role with provider secret",
+			resource:   resourceAwsRdsdataservicePostgresRole(),
+			id:         testResourceArn + "||app",
+			client:     &AWSClient{secretArn: testProviderSecretArn},
+			expectedID: "app",
+			expected: map[string]string{
+				"name":         "app",
+				"resource_arn": testResourceArn,
+				"secret_arn":   "",
+			},
+		},
+		{
+			name:       "grant role",
+			resource:   resourceAwsRdsdataservicePostgresGrantRole(),
+			id:         testResourceArn + "||app|readers|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "app|readers",
+			expected: map[string]string{
+				"role":         "app",
+				"grant_role":   "readers",
+				"resource_arn": testResourceArn,
+				"secret_arn":   testSecretArn,
+			},
+		},
+		{
+			name:       "schema",
+			resource:   resourceAwsRdsdataservicePostgresSchema(),
+			id:         testResourceArn + "|app|reporting|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "app.reporting",
+			expected: map[string]string{
+				"name":         "reporting",
+				"database":     "app",
+				"resource_arn": testResourceArn,
+				"secret_arn":   testSecretArn,
+			},
+		},
+		{
+			name:       "schema with provider defaults",
+			resource:   resourceAwsRdsdataservicePostgresSchema(),
+			id:         "||reporting",
+			client:     testProviderDefaultsClient(nil),
+			expectedID: "postgres.reporting",
+			expected: map[string]string{
+				"name":         "reporting",
+				"database":     "",
+				"resource_arn": "",
+				"secret_arn":   "",
+			},
+		},
+		{
+			name:       "schema in the provider database",
+			resource:   resourceAwsRdsdataservicePostgresSchema(),
+			id:         testProviderResourceArn + "|postgres|reporting",
+			client:     testProviderDefaultsClient(nil),
+			expectedID: "postgres.reporting",
+			expected: map[string]string{
+				"database":     "",
+				"resource_arn": "",
+			},
+		},
+		{
+			name:       "grant",
+			resource:   resourceAwsRdsdataservicePostgresGrant(),
+			id:         testResourceArn + "|app|app_user|public|table|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "app_user_app_public_table",
+			expected: map[string]string{
+				"role":        "app_user",
+				"database":    "app",
+				"schema":      "public",
+				"object_type": "table",
+			},
+		},
+		{
+			name:       "database grant",
+			resource:   resourceAwsRdsdataservicePostgresGrant(),
+			id:         testResourceArn + "|app|app_user||database|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "app_user_app__database",
+			expected: map[string]string{
+				"role":        "app_user",
+				"database":    "app",
+				"schema":      "",
+				"object_type": "database",
+			},
+		},
+		{
+			name:       "default privileges in every schema",
+			resource:   resourceAwsRdsdataservicePostgresDefaultPrivileges(),
+			id:         testResourceArn + "|app|reader|migrator||table|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "reader_app_migrator__table",
+			expected: map[string]string{
+				"role":        "reader",
+				"owner":       "migrator",
+				"database":    "app",
+				"schema":      "",
+				"object_type": "table",
+			},
+		},
+		{
+			name:       "extension",
+			resource:   resourceAwsRdsdataservicePostgresExtension(),
+			id:         testResourceArn + "|app|pgcrypto|" + testSecretArn,
+			client:     &AWSClient{},
+			expectedID: "app.pgcrypto",
+			expected: map[string]string{
+				"name":     "pgcrypto",
+				"database": "app",
+			},
+		},
+		{
+			name:       "grant in the provider database",
+			resource:   resourceAwsRdsdataservicePostgresGrant(),
+			id:         "||app_user|public|sequence",
+			client:     testProviderDefaultsClient(nil),
+			expectedID: "app_user_postgres_public_sequence",
+			expected: map[string]string{
+				"database": "",
+			},
+		},
+		{
+			name:       "schema with the postgres backend",
+			resource:   resourceAwsRdsdataservicePostgresSchema(),
+			id:         "||reporting",
+			client:     &AWSClient{backend: backendPostgres},
+			expectedID: "reporting",
+			expected: map[string]string{
+				"name":         "reporting",
+				"database":     "",
+				"resource_arn": "",
+				"secret_arn":   "",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			d, err := testImportState(t, testCase.resource, testCase.id, testCase.client)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if d.Id() != testCase.expectedID {
+				t.Errorf("got ID %q, expected %q", d.Id(), testCase.expectedID)
+			}
+			for attribute, want := range testCase.expected {
+				if got := d.Get(attribute).(string); got != want {
+					t.Errorf("%s: got %q, expected %q", attribute, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDataAPIImportMalformed(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resource *schema.Resource
+		id       string
+		client   *AWSClient
+		wantErr  string
+	}{
+		{
+			name:     "bare name",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			id:       "reporting",
+			client:   testProviderDefaultsClient(nil),
+			wantErr:  "expected <resource_arn>|<database>|<name>[|<secret_arn>]",
+		},
+		{
+			name:     "too many parts",
+			resource: resourceAwsRdsdataservicePostgresRole(),
+			id:       testResourceArn + "||app|" + testSecretArn + "|extra",
+			client:   &AWSClient{},
+			wantErr:  "expected <resource_arn>||<name>[|<secret_arn>]",
+		},
+		{
+			name:     "grant missing object type",
+			resource: resourceAwsRdsdataservicePostgresGrant(),
+			id:       testResourceArn + "|app|app_user|public",
+			client:   &AWSClient{secretArn: testSecretArn},
+			wantErr:  "expected <resource_arn>|<database>|<role>|<schema>|<object_type>[|<secret_arn>]",
+		},
+		{
+			name:     "no resource arn",
+			resource: resourceAwsRdsdataservicePostgresDatabase(),
+			id:       "||app|" + testSecretArn,
+			client:   &AWSClient{},
+			wantErr:  "no resource_arn",
+		},
+		{
+			name:     "no secret arn",
+			resource: resourceAwsRdsdataservicePostgresDatabase(),
+			id:       testResourceArn + "||app",
+			client:   &AWSClient{},
+			wantErr:  "no secret_arn",
+		},
+		{
+			name:     "no database",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			id:       testResourceArn + "||reporting|" + testSecretArn,
+			client:   &AWSClient{},
+			wantErr:  "no database",
+		},
+		{
+			name:     "database for a role",
+			resource: resourceAwsRdsdataservicePostgresRole(),
+			id:       testResourceArn + "|app|app_user|" + testSecretArn,
+			client:   &AWSClient{},
+			wantErr:  "leave the database part empty",
+		},
+		{
+			name:     "not an arn",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			id:       "my-cluster|app|reporting|" + testSecretArn,
+			client:   &AWSClient{},
+			wantErr:  `resource_arn "my-cluster" is not an ARN`,
+		},
+		{
+			name:     "empty name",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			id:       testResourceArn + "|app||" + testSecretArn,
+			client:   &AWSClient{},
+			wantErr:  "name is empty",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := testImportState(t, testCase.resource, testCase.id, testCase.client)
+			if err == nil || !strings.Contains(err.Error(), testCase.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+// The emulator serves the RDS Data API from a PostgreSQL server, so that the
+// provider can be run and tested without an Aurora Serverless cluster.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgdataapi"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8080", "address to serve the Data API on")
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "PostgreSQL connection URL or key=value connection string (default $DATABASE_URL)")
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("a connection string must be given with -dsn or DATABASE_URL")
+	}
+
+	executor, err := pgdataapi.Open(*dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Serving the RDS Data API on http://%s", *listen)
+	if err := http.ListenAndServe(*listen, pgdataapi.NewHandler(executor)); err != nil {
+		log.Fatal(err)
+	}
+}
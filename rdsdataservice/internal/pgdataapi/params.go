@@ -0,0 +1,173 @@
+package pgdataapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/lib/pq"
+)
+
+// Rewrite converts a statement using Data API named parameters (:name) into
+// one using PostgreSQL positional parameters ($1) and returns the values to
+// bind, in order. Placeholders inside string literals, quoted identifiers,
+// dollar-quoted strings and comments, and :: casts, are left alone. Every
+// placeholder must have a parameter, as with the Data API.
+func Rewrite(sql string, parameters []*rdsdataservice.SqlParameter) (string, []interface{}, error) {
+	values := make(map[string]*rdsdataservice.Field, len(parameters))
+	for _, parameter := range parameters {
+		values[aws.StringValue(parameter.Name)] = parameter.Value
+	}
+
+	var b strings.Builder
+	var args []interface{}
+	positions := make(map[string]int)
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+
+		switch {
+		case c == '\'' || c == '"':
+			end := quotedEnd(sql, i, c, c == '\'' && isEscapeString(sql, i))
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '$':
+			end := dollarQuotedEnd(sql, i)
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end == -1 {
+				end = len(sql) - i
+			}
+			b.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end == -1 {
+				end = len(sql) - i - 2
+			} else {
+				end += 2
+			}
+			b.WriteString(sql[i : i+2+end])
+			i += 2 + end
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			b.WriteString("::")
+			i += 2
+		case c == ':' && i+1 < len(sql) && isIdentStart(sql[i+1]):
+			end := i + 2
+			for end < len(sql) && isIdentChar(sql[end]) {
+				end++
+			}
+			name := sql[i+1 : end]
+
+			position, ok := positions[name]
+			if !ok {
+				field, ok := values[name]
+				if !ok {
+					return "", nil, fmt.Errorf("No value specified for parameter: %s", name)
+				}
+				value, err := FieldValue(field)
+				if err != nil {
+					return "", nil, fmt.Errorf("Invalid value for parameter %s: %s", name, err)
+				}
+				args = append(args, value)
+				position = len(args)
+				positions[name] = position
+			}
+
+			fmt.Fprintf(&b, "$%d", position)
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return b.String(), args, nil
+}
+
+// FieldValue returns the database/sql value of a Data API parameter.
+func FieldValue(field *rdsdataservice.Field) (interface{}, error) {
+	switch {
+	case field == nil || aws.BoolValue(field.IsNull):
+		return nil, nil
+	case field.BooleanValue != nil:
+		return *field.BooleanValue, nil
+	case field.LongValue != nil:
+		return *field.LongValue, nil
+	case field.DoubleValue != nil:
+		return *field.DoubleValue, nil
+	case field.StringValue != nil:
+		return *field.StringValue, nil
+	case field.BlobValue != nil:
+		return field.BlobValue, nil
+	case field.ArrayValue != nil:
+		array := field.ArrayValue
+		switch {
+		case array.StringValues != nil:
+			return pq.Array(aws.StringValueSlice(array.StringValues)), nil
+		case array.LongValues != nil:
+			return pq.Array(aws.Int64ValueSlice(array.LongValues)), nil
+		case array.BooleanValues != nil:
+			return pq.Array(aws.BoolValueSlice(array.BooleanValues)), nil
+		case array.DoubleValues != nil:
+			return pq.Array(aws.Float64ValueSlice(array.DoubleValues)), nil
+		}
+		return nil, fmt.Errorf("nested arrays are not supported")
+	}
+	return nil, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+// isEscapeString reports whether the string literal starting at i is an
+// E'...' literal, in which backslash escapes the next character.
+func isEscapeString(sql string, i int) bool {
+	if i == 0 || (sql[i-1] != 'E' && sql[i-1] != 'e') {
+		return false
+	}
+	return i == 1 || !isIdentChar(sql[i-2])
+}
+
+// quotedEnd returns the index just past the literal or identifier quoted
+// with q that starts at i. Doubled quotes do not end it.
+func quotedEnd(sql string, i int, q byte, backslash bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case backslash && sql[j] == '\\':
+			j++
+		case sql[j] == q && j+1 < len(sql) && sql[j+1] == q:
+			j++
+		case sql[j] == q:
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarQuotedEnd returns the index just past the dollar-quoted string
+// starting at i, or i+1 if the $ does not open one.
+func dollarQuotedEnd(sql string, i int) int {
+	j := i + 1
+	for j < len(sql) && sql[j] != '$' && isIdentChar(sql[j]) {
+		j++
+	}
+	if j == len(sql) || sql[j] != '$' || (j > i+1 && !isIdentStart(sql[i+1])) {
+		return i + 1
+	}
+
+	tag := sql[i : j+1]
+	end := strings.Index(sql[j+1:], tag)
+	if end == -1 {
+		return len(sql)
+	}
+	return j + 1 + end + len(tag)
+}
@@ -0,0 +1,152 @@
+package pgdataapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/lib/pq"
+)
+
+func stringParameter(name, value string) *rdsdataservice.SqlParameter {
+	return &rdsdataservice.SqlParameter{
+		Name:  aws.String(name),
+		Value: &rdsdataservice.Field{StringValue: aws.String(value)},
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	testCases := []struct {
+		name       string
+		sql        string
+		parameters []*rdsdataservice.SqlParameter
+		wantSQL    string
+		wantArgs   []interface{}
+	}{
+		{
+			name:       "single parameter",
+			sql:        "SELECT 1 FROM pg_roles WHERE rolname = :name",
+			parameters: []*rdsdataservice.SqlParameter{stringParameter("name", "app")},
+			wantSQL:    "SELECT 1 FROM pg_roles WHERE rolname = $1",
+			wantArgs:   []interface{}{"app"},
+		},
+		{
+			name: "repeated parameter",
+			sql:  "SELECT :a, :b, :a",
+			parameters: []*rdsdataservice.SqlParameter{
+				stringParameter("b", "2"),
+				stringParameter("a", "1"),
+			},
+			wantSQL:  "SELECT $1, $2, $1",
+			wantArgs: []interface{}{"1", "2"},
+		},
+		{
+			name:       "cast",
+			sql:        "SELECT :name::regrole",
+			parameters: []*rdsdataservice.SqlParameter{stringParameter("name", "app")},
+			wantSQL:    "SELECT $1::regrole",
+			wantArgs:   []interface{}{"app"},
+		},
+		{
+			name:    "string literal",
+			sql:     "SELECT ':name', 'it'':s'",
+			wantSQL: "SELECT ':name', 'it'':s'",
+		},
+		{
+			name:    "escape string",
+			sql:     `SELECT E'\':name'`,
+			wantSQL: `SELECT E'\':name'`,
+		},
+		{
+			name:    "quoted identifier",
+			sql:     `CREATE ROLE "a:name"`,
+			wantSQL: `CREATE ROLE "a:name"`,
+		},
+		{
+			name:    "dollar quote",
+			sql:     "DO $body$ SELECT :name $body$",
+			wantSQL: "DO $body$ SELECT :name $body$",
+		},
+		{
+			name:    "comments",
+			sql:     "SELECT 1 -- :name\n/* :name */",
+			wantSQL: "SELECT 1 -- :name\n/* :name */",
+		},
+		{
+			name:    "positional parameter",
+			sql:     "SELECT $1",
+			wantSQL: "SELECT $1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sql, args, err := Rewrite(testCase.sql, testCase.parameters)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if sql != testCase.wantSQL {
+				t.Errorf("got %q, expected %q", sql, testCase.wantSQL)
+			}
+			if !reflect.DeepEqual(args, testCase.wantArgs) {
+				t.Errorf("got args %#v, expected %#v", args, testCase.wantArgs)
+			}
+		})
+	}
+}
+
+func TestRewriteMissingParameter(t *testing.T) {
+	_, _, err := Rewrite("SELECT :name", nil)
+	if err == nil || err.Error() != "No value specified for parameter: name" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	testCases := []struct {
+		name  string
+		field *rdsdataservice.Field
+		want  interface{}
+	}{
+		{
+			name:  "null",
+			field: &rdsdataservice.Field{IsNull: aws.Bool(true)},
+			want:  nil,
+		},
+		{
+			name:  "bool",
+			field: &rdsdataservice.Field{BooleanValue: aws.Bool(true)},
+			want:  true,
+		},
+		{
+			name:  "long",
+			field: &rdsdataservice.Field{LongValue: aws.Int64(42)},
+			want:  int64(42),
+		},
+		{
+			name:  "string",
+			field: &rdsdataservice.Field{StringValue: aws.String("app")},
+			want:  "app",
+		},
+		{
+			name: "string array",
+			field: &rdsdataservice.Field{ArrayValue: &rdsdataservice.ArrayValue{
+				StringValues: aws.StringSlice([]string{"a", "b"}),
+			}},
+			want: pq.Array([]string{"a", "b"}),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := FieldValue(testCase.field)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("got %#v, expected %#v", got, testCase.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+// Package pgdataapi runs RDS Data API requests against PostgreSQL over a
+// direct lib/pq connection. It accepts and returns the aws-sdk-go
+// rdsdataservice types, so callers written against the Data API work
+// unchanged against provisioned Aurora clusters and plain PostgreSQL.
+//
+// Resource and secret ARNs are ignored: the connection string decides which
+// server and credentials are used. The Database of a request selects the
+// database to connect to, and defaults to the one in the connection string.
+package pgdataapi
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/lib/pq"
+)
+
+// Executor runs Data API requests on a PostgreSQL server.
+type Executor struct {
+	conninfo string
+
+	mu           sync.Mutex
+	dbs          map[string]*sql.DB
+	transactions map[string]*sql.Tx
+}
+
+// Open returns an Executor for the server described by dsn, either a
+// postgres:// URL or a key=value connection string. No connection is made
+// until the first request, so a key=value string is only checked then.
+func Open(dsn string) (*Executor, error) {
+	conninfo := dsn
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if conninfo, err = pq.ParseURL(dsn); err != nil {
+			return nil, fmt.Errorf("Error parsing PostgreSQL connection URL: %s", err)
+		}
+	}
+
+	return &Executor{
+		conninfo:     conninfo,
+		dbs:          make(map[string]*sql.DB),
+		transactions: make(map[string]*sql.Tx),
+	}, nil
+}
+
+// Close closes every connection and rolls back open transactions.
+func (e *Executor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, tx := range e.transactions {
+		tx.Rollback()
+		delete(e.transactions, id)
+	}
+	for database, db := range e.dbs {
+		db.Close()
+		delete(e.dbs, database)
+	}
+	return nil
+}
+
+func (e *Executor) db(database string) (*sql.DB, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if db, ok := e.dbs[database]; ok {
+		return db, nil
+	}
+
+	conninfo := e.conninfo
+	if database != "" {
+		// Later keys override earlier ones in a connection string
+		conninfo += " dbname=" + QuoteConninfo(database)
+	}
+
+	db, err := sql.Open("postgres", conninfo)
+	if err != nil {
+		return nil, err
+	}
+	e.dbs[database] = db
+	return db, nil
+}
+
+func (e *Executor) transaction(id *string) (*sql.Tx, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	tx, ok := e.transactions[aws.StringValue(id)]
+	if !ok {
+		return nil, awserr.New(rdsdataservice.ErrCodeNotFoundException, fmt.Sprintf("Transaction %s is not found", aws.StringValue(id)), nil)
+	}
+	return tx, nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (e *Executor) queryer(database string, transactionID *string) (queryer, error) {
+	if aws.StringValue(transactionID) != "" {
+		return e.transaction(transactionID)
+	}
+	db, err := e.db(database)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return db, nil
+}
+
+func (e *Executor) ExecuteStatement(input *rdsdataservice.ExecuteStatementInput) (*rdsdataservice.ExecuteStatementOutput, error) {
+	q, err := e.queryer(aws.StringValue(input.Database), input.TransactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := Rewrite(aws.StringValue(input.Sql), input.Parameters)
+	if err != nil {
+		return nil, awserr.New(rdsdataservice.ErrCodeBadRequestException, err.Error(), nil)
+	}
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	defer rows.Close()
+
+	output := &rdsdataservice.ExecuteStatementOutput{}
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, apiError(err)
+	}
+	if aws.BoolValue(input.IncludeResultMetadata) {
+		for _, column := range columns {
+			output.ColumnMetadata = append(output.ColumnMetadata, &rdsdataservice.ColumnMetadata{
+				Name:     aws.String(column.Name()),
+				Label:    aws.String(column.Name()),
+				TypeName: aws.String(strings.ToLower(column.DatabaseTypeName())),
+			})
+		}
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, apiError(err)
+		}
+
+		record := make([]*rdsdataservice.Field, len(columns))
+		for i, value := range values {
+			record[i] = Field(value, columns[i].DatabaseTypeName())
+		}
+		output.Records = append(output.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apiError(err)
+	}
+
+	if len(columns) == 0 {
+		output.NumberOfRecordsUpdated = aws.Int64(0)
+	}
+
+	return output, nil
+}
+
+func (e *Executor) BatchExecuteStatement(input *rdsdataservice.BatchExecuteStatementInput) (*rdsdataservice.BatchExecuteStatementOutput, error) {
+	output := &rdsdataservice.BatchExecuteStatementOutput{}
+
+	parameterSets := input.ParameterSets
+	if len(parameterSets) == 0 {
+		parameterSets = [][]*rdsdataservice.SqlParameter{nil}
+	}
+
+	for _, parameters := range parameterSets {
+		result, err := e.ExecuteStatement(&rdsdataservice.ExecuteStatementInput{
+			Database:      input.Database,
+			Parameters:    parameters,
+			Sql:           input.Sql,
+			TransactionId: input.TransactionId,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		updateResult := &rdsdataservice.UpdateResult{}
+		if len(result.Records) > 0 {
+			updateResult.GeneratedFields = result.Records[0]
+		}
+		output.UpdateResults = append(output.UpdateResults, updateResult)
+	}
+
+	return output, nil
+}
+
+func (e *Executor) BeginTransaction(input *rdsdataservice.BeginTransactionInput) (*rdsdataservice.BeginTransactionOutput, error) {
+	db, err := e.db(aws.StringValue(input.Database))
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	id, err := transactionID()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	e.mu.Lock()
+	e.transactions[id] = tx
+	e.mu.Unlock()
+
+	return &rdsdataservice.BeginTransactionOutput{TransactionId: aws.String(id)}, nil
+}
+
+func (e *Executor) CommitTransaction(input *rdsdataservice.CommitTransactionInput) (*rdsdataservice.CommitTransactionOutput, error) {
+	tx, err := e.endTransaction(input.TransactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apiError(err)
+	}
+
+	return &rdsdataservice.CommitTransactionOutput{TransactionStatus: aws.String("Transaction Committed")}, nil
+}
+
+func (e *Executor) RollbackTransaction(input *rdsdataservice.RollbackTransactionInput) (*rdsdataservice.RollbackTransactionOutput, error) {
+	tx, err := e.endTransaction(input.TransactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Rollback(); err != nil {
+		return nil, apiError(err)
+	}
+
+	return &rdsdataservice.RollbackTransactionOutput{TransactionStatus: aws.String("Rollback Complete")}, nil
+}
+
+func (e *Executor) endTransaction(id *string) (*sql.Tx, error) {
+	tx, err := e.transaction(id)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	delete(e.transactions, aws.StringValue(id))
+	e.mu.Unlock()
+
+	return tx, nil
+}
+
+// Field returns the Data API field for a value scanned by lib/pq from a
+// column of the given database type.
+func Field(value interface{}, databaseType string) *rdsdataservice.Field {
+	switch v := value.(type) {
+	case nil:
+		return &rdsdataservice.Field{IsNull: aws.Bool(true)}
+	case bool:
+		return &rdsdataservice.Field{BooleanValue: aws.Bool(v)}
+	case int64:
+		return &rdsdataservice.Field{LongValue: aws.Int64(v)}
+	case float64:
+		return &rdsdataservice.Field{DoubleValue: aws.Float64(v)}
+	case string:
+		return &rdsdataservice.Field{StringValue: aws.String(v)}
+	case []byte:
+		// lib/pq returns the text form of types it does not decode, like
+		// name, numeric and arrays, as bytes too.
+		if strings.EqualFold(databaseType, "BYTEA") {
+			return &rdsdataservice.Field{BlobValue: v}
+		}
+		if strings.HasPrefix(databaseType, "_") {
+			if array := arrayValue(v, strings.ToUpper(databaseType[1:])); array != nil {
+				return &rdsdataservice.Field{ArrayValue: array}
+			}
+		}
+		return &rdsdataservice.Field{StringValue: aws.String(string(v))}
+	case time.Time:
+		return &rdsdataservice.Field{StringValue: aws.String(v.Format("2006-01-02 15:04:05.999999"))}
+	}
+	return &rdsdataservice.Field{StringValue: aws.String(fmt.Sprint(value))}
+}
+
+// arrayValue returns the Data API array for the text form of a
+// one-dimensional array with elements of the given type, or nil if it cannot
+// be decoded. Elements of other types, like aclitem, are returned as
+// strings, as the Data API does.
+func arrayValue(src []byte, elementType string) *rdsdataservice.ArrayValue {
+	switch elementType {
+	case "BOOL":
+		var a pq.BoolArray
+		if a.Scan(src) == nil {
+			return &rdsdataservice.ArrayValue{BooleanValues: aws.BoolSlice(a)}
+		}
+	case "INT2", "INT4", "INT8", "OID":
+		var a pq.Int64Array
+		if a.Scan(src) == nil {
+			return &rdsdataservice.ArrayValue{LongValues: aws.Int64Slice(a)}
+		}
+	case "FLOAT4", "FLOAT8":
+		var a pq.Float64Array
+		if a.Scan(src) == nil {
+			return &rdsdataservice.ArrayValue{DoubleValues: aws.Float64Slice(a)}
+		}
+	default:
+		var a pq.StringArray
+		if a.Scan(src) == nil {
+			return &rdsdataservice.ArrayValue{StringValues: aws.StringSlice(a)}
+		}
+	}
+	return nil
+}
+
+// apiError converts a database error into the error the Data API would
+// return for it, so callers can handle both backends alike.
+func apiError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return awserr.New(rdsdataservice.ErrCodeBadRequestException, fmt.Sprintf("%s: %s", pqErr.Severity, pqErr.Message), err)
+	}
+	return awserr.New(rdsdataservice.ErrCodeInternalServerErrorException, err.Error(), err)
+}
+
+func transactionID() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// QuoteConninfo quotes a value for a key=value connection string.
+func QuoteConninfo(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `'`, `\'`, -1)
+	return "'" + value + "'"
+}
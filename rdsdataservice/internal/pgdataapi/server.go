@@ -0,0 +1,135 @@
+package pgdataapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+)
+
+// API is the part of the Data API served by NewHandler. *Executor
+// implements it.
+type API interface {
+	ExecuteStatement(*rdsdataservice.ExecuteStatementInput) (*rdsdataservice.ExecuteStatementOutput, error)
+	BatchExecuteStatement(*rdsdataservice.BatchExecuteStatementInput) (*rdsdataservice.BatchExecuteStatementOutput, error)
+	BeginTransaction(*rdsdataservice.BeginTransactionInput) (*rdsdataservice.BeginTransactionOutput, error)
+	CommitTransaction(*rdsdataservice.CommitTransactionInput) (*rdsdataservice.CommitTransactionOutput, error)
+	RollbackTransaction(*rdsdataservice.RollbackTransactionInput) (*rdsdataservice.RollbackTransactionOutput, error)
+}
+
+// operation is a single Data API call: the input to decode the request
+// body into, and the call to make once it is decoded.
+type operation struct {
+	input interface{ Validate() error }
+	call  func() (interface{}, error)
+}
+
+// NewHandler returns an http.Handler serving the Data API REST-JSON
+// protocol, as used by the aws-sdk-go rdsdataservice client, from api.
+// Requests are not authenticated.
+func NewHandler(api API) http.Handler {
+	mux := http.NewServeMux()
+	handle := func(path string, newOperation func() operation) {
+		mux.Handle(path, operationHandler(path, newOperation))
+	}
+
+	handle("/Execute", func() operation {
+		input := &rdsdataservice.ExecuteStatementInput{}
+		return operation{input, func() (interface{}, error) { return api.ExecuteStatement(input) }}
+	})
+	handle("/BatchExecute", func() operation {
+		input := &rdsdataservice.BatchExecuteStatementInput{}
+		return operation{input, func() (interface{}, error) { return api.BatchExecuteStatement(input) }}
+	})
+	handle("/BeginTransaction", func() operation {
+		input := &rdsdataservice.BeginTransactionInput{}
+		return operation{input, func() (interface{}, error) { return api.BeginTransaction(input) }}
+	})
+	handle("/CommitTransaction", func() operation {
+		input := &rdsdataservice.CommitTransactionInput{}
+		return operation{input, func() (interface{}, error) { return api.CommitTransaction(input) }}
+	})
+	handle("/RollbackTransaction", func() operation {
+		input := &rdsdataservice.RollbackTransactionInput{}
+		return operation{input, func() (interface{}, error) { return api.RollbackTransaction(input) }}
+	})
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, awserr.New("UnknownOperationException", "Unsupported operation: "+r.URL.Path, nil))
+	})
+
+	return mux
+}
+
+func operationHandler(path string, newOperation func() operation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, awserr.New(rdsdataservice.ErrCodeBadRequestException, "Method not allowed: "+r.Method, nil))
+			return
+		}
+
+		op := newOperation()
+		if err := jsonutil.UnmarshalJSON(op.input, r.Body); err != nil {
+			writeError(w, awserr.New(rdsdataservice.ErrCodeBadRequestException, "Invalid request body: "+err.Error(), nil))
+			return
+		}
+		if err := op.input.Validate(); err != nil {
+			writeError(w, awserr.New(rdsdataservice.ErrCodeBadRequestException, err.Error(), nil))
+			return
+		}
+
+		// The statements are not logged, as they may contain passwords
+		log.Printf("[DEBUG] %s", path)
+		output, err := op.call()
+		if err != nil {
+			log.Printf("[DEBUG] %s failed: %s", path, err)
+			writeError(w, err)
+			return
+		}
+
+		body, err := jsonutil.BuildJSON(output)
+		if err != nil {
+			writeError(w, awserr.New(rdsdataservice.ErrCodeInternalServerErrorException, err.Error(), nil))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+// errorStatus maps Data API error codes to the HTTP status the service
+// returns them with.
+var errorStatus = map[string]int{
+	rdsdataservice.ErrCodeBadRequestException:          http.StatusBadRequest,
+	rdsdataservice.ErrCodeStatementTimeoutException:    http.StatusBadRequest,
+	rdsdataservice.ErrCodeForbiddenException:           http.StatusForbidden,
+	rdsdataservice.ErrCodeNotFoundException:            http.StatusNotFound,
+	"UnknownOperationException":                        http.StatusNotFound,
+	rdsdataservice.ErrCodeServiceUnavailableError:      http.StatusServiceUnavailable,
+	rdsdataservice.ErrCodeInternalServerErrorException: http.StatusInternalServerError,
+}
+
+// writeError writes err the way the Data API does: the code in the
+// X-Amzn-Errortype header and the message in the body.
+func writeError(w http.ResponseWriter, err error) {
+	code, message := rdsdataservice.ErrCodeInternalServerErrorException, err.Error()
+	if awsErr, ok := err.(awserr.Error); ok {
+		code, message = awsErr.Code(), awsErr.Message()
+	}
+
+	status, ok := errorStatus[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	body, _ := json.Marshal(map[string]string{"message": message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Amzn-Errortype", code)
+	w.WriteHeader(status)
+	w.Write(body)
+}
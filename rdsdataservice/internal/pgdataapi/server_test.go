@@ -0,0 +1,252 @@
+package pgdataapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/lib/pq"
+)
+
+const (
+	testResourceArn = "arn:aws:rds:us-east-1:123456789012:cluster:emulator"
+	testSecretArn   = "arn:aws:secretsmanager:us-east-1:123456789012:secret:emulator"
+)
+
+// fakeAPI answers every call with a fixed output or error and records the
+// inputs it received.
+type fakeAPI struct {
+	inputs []interface{}
+	err    error
+
+	executeOutput *rdsdataservice.ExecuteStatementOutput
+}
+
+func (f *fakeAPI) ExecuteStatement(input *rdsdataservice.ExecuteStatementInput) (*rdsdataservice.ExecuteStatementOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return f.executeOutput, f.err
+}
+
+func (f *fakeAPI) BatchExecuteStatement(input *rdsdataservice.BatchExecuteStatementInput) (*rdsdataservice.BatchExecuteStatementOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &rdsdataservice.BatchExecuteStatementOutput{}, f.err
+}
+
+func (f *fakeAPI) BeginTransaction(input *rdsdataservice.BeginTransactionInput) (*rdsdataservice.BeginTransactionOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &rdsdataservice.BeginTransactionOutput{TransactionId: aws.String("tx")}, f.err
+}
+
+func (f *fakeAPI) CommitTransaction(input *rdsdataservice.CommitTransactionInput) (*rdsdataservice.CommitTransactionOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &rdsdataservice.CommitTransactionOutput{TransactionStatus: aws.String("Transaction Committed")}, f.err
+}
+
+func (f *fakeAPI) RollbackTransaction(input *rdsdataservice.RollbackTransactionInput) (*rdsdataservice.RollbackTransactionOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &rdsdataservice.RollbackTransactionOutput{TransactionStatus: aws.String("Rollback Complete")}, f.err
+}
+
+// testClient returns an SDK client talking to server, the way the provider
+// does with an rdsdataservice endpoint override.
+func testClient(t *testing.T, server *httptest.Server) *rdsdataservice.RDSDataService {
+	t.Helper()
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+		Endpoint:    aws.String(server.URL),
+		MaxRetries:  aws.Int(0),
+		Region:      aws.String("us-east-1"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return rdsdataservice.New(sess)
+}
+
+func TestHandlerExecuteStatement(t *testing.T) {
+	output := &rdsdataservice.ExecuteStatementOutput{
+		ColumnMetadata: []*rdsdataservice.ColumnMetadata{
+			{Name: aws.String("rolname"), TypeName: aws.String("name")},
+			{Name: aws.String("rolconnlimit"), TypeName: aws.String("int4")},
+			{Name: aws.String("rolsuper"), TypeName: aws.String("bool")},
+			{Name: aws.String("rolvaliduntil"), TypeName: aws.String("timestamptz")},
+			{Name: aws.String("data"), TypeName: aws.String("bytea")},
+		},
+		Records: [][]*rdsdataservice.Field{{
+			{StringValue: aws.String("app")},
+			{LongValue: aws.Int64(-1)},
+			{BooleanValue: aws.Bool(false)},
+			{IsNull: aws.Bool(true)},
+			{BlobValue: []byte{0, 1, 2}},
+		}},
+	}
+	api := &fakeAPI{executeOutput: output}
+	server := httptest.NewServer(NewHandler(api))
+	defer server.Close()
+	conn := testClient(t, server)
+
+	input := &rdsdataservice.ExecuteStatementInput{
+		ResourceArn:           aws.String(testResourceArn),
+		SecretArn:             aws.String(testSecretArn),
+		Database:              aws.String("app"),
+		Sql:                   aws.String("SELECT * FROM pg_roles WHERE rolname = :name"),
+		IncludeResultMetadata: aws.Bool(true),
+		Parameters: []*rdsdataservice.SqlParameter{{
+			Name:  aws.String("name"),
+			Value: &rdsdataservice.Field{StringValue: aws.String("app")},
+		}},
+	}
+	got, err := conn.ExecuteStatement(input)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !reflect.DeepEqual(got.Records, output.Records) {
+		t.Errorf("got records %s, expected %s", got.Records, output.Records)
+	}
+	if !reflect.DeepEqual(got.ColumnMetadata, output.ColumnMetadata) {
+		t.Errorf("got metadata %s, expected %s", got.ColumnMetadata, output.ColumnMetadata)
+	}
+	if len(api.inputs) != 1 || !reflect.DeepEqual(api.inputs[0], input) {
+		t.Errorf("got inputs %s, expected %s", api.inputs, input)
+	}
+}
+
+func TestHandlerTransactions(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(NewHandler(api))
+	defer server.Close()
+	conn := testClient(t, server)
+
+	begin, err := conn.BeginTransaction(&rdsdataservice.BeginTransactionInput{
+		ResourceArn: aws.String(testResourceArn),
+		SecretArn:   aws.String(testSecretArn),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if aws.StringValue(begin.TransactionId) != "tx" {
+		t.Fatalf("got transaction ID %q", aws.StringValue(begin.TransactionId))
+	}
+
+	_, err = conn.BatchExecuteStatement(&rdsdataservice.BatchExecuteStatementInput{
+		ResourceArn:   aws.String(testResourceArn),
+		SecretArn:     aws.String(testSecretArn),
+		Sql:           aws.String("INSERT INTO t VALUES (:v)"),
+		TransactionId: begin.TransactionId,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	commit, err := conn.CommitTransaction(&rdsdataservice.CommitTransactionInput{
+		ResourceArn:   aws.String(testResourceArn),
+		SecretArn:     aws.String(testSecretArn),
+		TransactionId: begin.TransactionId,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if aws.StringValue(commit.TransactionStatus) != "Transaction Committed" {
+		t.Errorf("got status %q", aws.StringValue(commit.TransactionStatus))
+	}
+
+	_, err = conn.RollbackTransaction(&rdsdataservice.RollbackTransactionInput{
+		ResourceArn:   aws.String(testResourceArn),
+		SecretArn:     aws.String(testSecretArn),
+		TransactionId: begin.TransactionId,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(api.inputs) != 4 {
+		t.Errorf("expected 4 calls, got %d", len(api.inputs))
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		wantCode    string
+		wantMessage string
+		wantStatus  int
+	}{
+		{
+			name:        "database error",
+			err:         apiError(&pq.Error{Severity: "ERROR", Message: `role "app" already exists`}),
+			wantCode:    rdsdataservice.ErrCodeBadRequestException,
+			wantMessage: `ERROR: role "app" already exists`,
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "unknown transaction",
+			err:         awserr.New(rdsdataservice.ErrCodeNotFoundException, "Transaction tx is not found", nil),
+			wantCode:    rdsdataservice.ErrCodeNotFoundException,
+			wantMessage: "Transaction tx is not found",
+			wantStatus:  http.StatusNotFound,
+		},
+		{
+			name:        "connection error",
+			err:         apiError(errors.New("dial tcp 127.0.0.1:5432: connect: connection refused")),
+			wantCode:    rdsdataservice.ErrCodeInternalServerErrorException,
+			wantMessage: "dial tcp 127.0.0.1:5432: connect: connection refused",
+			wantStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server := httptest.NewServer(NewHandler(&fakeAPI{err: testCase.err}))
+			defer server.Close()
+			conn := testClient(t, server)
+
+			_, err := conn.ExecuteStatement(&rdsdataservice.ExecuteStatementInput{
+				ResourceArn: aws.String(testResourceArn),
+				SecretArn:   aws.String(testSecretArn),
+				Sql:         aws.String("CREATE ROLE app"),
+			})
+
+			awsErr, ok := err.(awserr.RequestFailure)
+			if !ok {
+				t.Fatalf("expected an awserr.RequestFailure, got %#v", err)
+			}
+			if awsErr.Code() != testCase.wantCode {
+				t.Errorf("got code %q, expected %q", awsErr.Code(), testCase.wantCode)
+			}
+			if awsErr.Message() != testCase.wantMessage {
+				t.Errorf("got message %q, expected %q", awsErr.Message(), testCase.wantMessage)
+			}
+			if awsErr.StatusCode() != testCase.wantStatus {
+				t.Errorf("got status %d, expected %d", awsErr.StatusCode(), testCase.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandlerUnknownOperation(t *testing.T) {
+	server := httptest.NewServer(NewHandler(&fakeAPI{}))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/ExecuteSql", "application/json", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d", resp.StatusCode)
+	}
+	if code := resp.Header.Get("X-Amzn-Errortype"); code != "UnknownOperationException" {
+		t.Errorf("got error type %q", code)
+	}
+}
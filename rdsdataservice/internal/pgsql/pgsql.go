@@ -0,0 +1,78 @@
+// Package pgsql builds PostgreSQL statements from user supplied names and
+// values. Every DDL statement issued by the provider goes through Format so
+// that identifiers are always quoted and literals always escaped the same way,
+// whatever characters the configuration contains.
+package pgsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ident quotes name for use as an SQL identifier. The result preserves case
+// and may contain any character, including spaces, hyphens and double
+// quotes. PostgreSQL cannot store NUL bytes, but they are kept rather than
+// truncated at, so that a statement naming one fails instead of acting on a
+// shorter name; callers should reject them beforehand.
+func Ident(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// Literal quotes value for use as an SQL string literal. Backslashes are
+// escaped with the E'...' syntax so the result is correct whatever the value of
+// standard_conforming_strings. Like Ident, NUL bytes are kept.
+func Literal(value string) string {
+	value = strings.Replace(value, `'`, `''`, -1)
+	if strings.Contains(value, `\`) {
+		return `E'` + strings.Replace(value, `\`, `\\`, -1) + `'`
+	}
+	return `'` + value + `'`
+}
+
+// Format builds a statement in the style of PostgreSQL's format() function.
+// It supports the following verbs:
+//
+//	%I  the argument quoted as an identifier (see Ident)
+//	%L  the argument quoted as a string literal (see Literal)
+//	%%  a literal percent sign
+//
+// Any other verb, such as %s or %d, is handled by the fmt package and its
+// argument is written verbatim. Verbatim arguments must never come from user
+// input; use them only for keywords and numbers.
+func Format(format string, args ...interface{}) string {
+	var b strings.Builder
+	argNum := 0
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i+1 == len(format) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		verb := format[i]
+		if verb == '%' {
+			b.WriteByte('%')
+			continue
+		}
+
+		if argNum >= len(args) {
+			fmt.Fprintf(&b, "%%!%c(MISSING)", verb)
+			continue
+		}
+		arg := args[argNum]
+		argNum++
+
+		switch verb {
+		case 'I':
+			b.WriteString(Ident(fmt.Sprint(arg)))
+		case 'L':
+			b.WriteString(Literal(fmt.Sprint(arg)))
+		default:
+			fmt.Fprintf(&b, "%"+string(verb), arg)
+		}
+	}
+
+	return b.String()
+}
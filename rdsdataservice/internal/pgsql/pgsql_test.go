@@ -0,0 +1,160 @@
+package pgsql
+
+import (
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestIdent(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "simple",
+			input: "app",
+			want:  `"app"`,
+		},
+		{
+			name:  "mixed case",
+			input: "AppUser",
+			want:  `"AppUser"`,
+		},
+		{
+			name:  "hyphen",
+			input: "app-user",
+			want:  `"app-user"`,
+		},
+		{
+			name:  "double quote",
+			input: `a"b`,
+			want:  `"a""b"`,
+		},
+		{
+			name:  "nul byte",
+			input: "app\x00; DROP ROLE root",
+			want:  "\"app\x00; DROP ROLE root\"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := Ident(testCase.input); got != testCase.want {
+				t.Errorf("got %s, expected %s", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "simple",
+			input: "secret",
+			want:  `'secret'`,
+		},
+		{
+			name:  "single quote",
+			input: "it's",
+			want:  `'it''s'`,
+		},
+		{
+			name:  "backslash",
+			input: `a\b'c`,
+			want:  `E'a\\b''c'`,
+		},
+		{
+			name:  "empty",
+			input: "",
+			want:  `''`,
+		},
+		{
+			name:  "nul byte",
+			input: "secret\x00",
+			want:  "'secret\x00'",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := Literal(testCase.input); got != testCase.want {
+				t.Errorf("got %s, expected %s", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		args   []interface{}
+		want   string
+	}{
+		{
+			name:   "identifiers",
+			format: "CREATE DATABASE %I OWNER %I",
+			args:   []interface{}{"App-DB", "owner"},
+			want:   `CREATE DATABASE "App-DB" OWNER "owner"`,
+		},
+		{
+			name:   "literal",
+			format: "ALTER ROLE %I WITH PASSWORD %L",
+			args:   []interface{}{"app", "p'w"},
+			want:   `ALTER ROLE "app" WITH PASSWORD 'p''w'`,
+		},
+		{
+			name:   "verbatim",
+			format: "ALTER ROLE %I WITH %s CONNECTION LIMIT %d",
+			args:   []interface{}{"app", "LOGIN", 5},
+			want:   `ALTER ROLE "app" WITH LOGIN CONNECTION LIMIT 5`,
+		},
+		{
+			name:   "percent",
+			format: "SELECT 100%% FROM %I",
+			args:   []interface{}{"t"},
+			want:   `SELECT 100% FROM "t"`,
+		},
+		{
+			name:   "missing",
+			format: "DROP ROLE %I",
+			want:   `DROP ROLE %!I(MISSING)`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := Format(testCase.format, testCase.args...); got != testCase.want {
+				t.Errorf("got %s, expected %s", got, testCase.want)
+			}
+		})
+	}
+}
+
+// unquote reverses Ident and Literal the way the PostgreSQL lexer would.
+func unquote(quoted string) string {
+	escape := strings.HasPrefix(quoted, "E'")
+	quoted = strings.TrimPrefix(quoted, "E")
+	q := quoted[:1]
+	body := strings.Replace(quoted[1:len(quoted)-1], q+q, q, -1)
+	if escape {
+		body = strings.Replace(body, `\\`, `\`, -1)
+	}
+	return body
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	roundTrip := func(s string) bool {
+		return unquote(Ident(s)) == s && unquote(Literal(s)) == s
+	}
+
+	if err := quick.Check(roundTrip, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,10 +1,13 @@
 package rdsdataservice
 
 import (
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/mutexkv"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 	"github.com/hashicorp/terraform-plugin-sdk/terraform"
 	homedir "github.com/mitchellh/go-homedir"
 )
@@ -56,13 +59,12 @@ func Provider() terraform.ResourceProvider {
 
 			"region": {
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
 				DefaultFunc: schema.MultiEnvDefaultFunc([]string{
 					"AWS_REGION",
 					"AWS_DEFAULT_REGION",
 				}, nil),
-				Description:  descriptions["region"],
-				InputDefault: "us-east-1",
+				Description: descriptions["region"],
 			},
 
 			"max_retries": {
@@ -154,13 +156,54 @@ func Provider() terraform.ResourceProvider {
 				Default:     false,
 				Description: descriptions["s3_force_path_style"],
 			},
+
+			"resource_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: descriptions["resource_arn"],
+			},
+
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: descriptions["secret_arn"],
+			},
+
+			"database": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  descriptions["database"],
+			},
+
+			"resume_timeout": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "5m",
+				ValidateFunc: validateDuration,
+				Description:  descriptions["resume_timeout"],
+			},
+
+			"backend": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      backendDataAPI,
+				ValidateFunc: validation.StringInSlice([]string{backendDataAPI, backendPostgres}, false),
+				Description:  descriptions["backend"],
+			},
+
+			"postgres": postgresSchema(),
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"rdsdataservice_postgres_database": resourceAwsRdsdataservicePostgresDatabase(),
-			"rdsdataservice_postgres_schema": resourceAwsRdsdataservicePostgresSchema(),
-			"rdsdataservice_postgres_role":     resourceAwsRdsdataservicePostgresRole(),
-			"rdsdataservice_postgres_grant":	resourceAwsRdsdataservicePostgresGrant(),
+			"rdsdataservice_postgres_database":           resourceAwsRdsdataservicePostgresDatabase(),
+			"rdsdataservice_postgres_schema":             resourceAwsRdsdataservicePostgresSchema(),
+			"rdsdataservice_postgres_role":               resourceAwsRdsdataservicePostgresRole(),
+			"rdsdataservice_postgres_role_credentials":   resourceAwsRdsdataservicePostgresRoleCredentials(),
+			"rdsdataservice_postgres_grant":              resourceAwsRdsdataservicePostgresGrant(),
+			"rdsdataservice_postgres_grant_role":         resourceAwsRdsdataservicePostgresGrantRole(),
+			"rdsdataservice_postgres_default_privileges": resourceAwsRdsdataservicePostgresDefaultPrivileges(),
+			"rdsdataservice_postgres_extension":          resourceAwsRdsdataservicePostgresExtension(),
 		},
 	}
 
@@ -183,7 +226,7 @@ var endpointServiceNames []string
 func init() {
 	descriptions = map[string]string{
 		"region": "The region where AWS operations will take place. Examples\n" +
-			"are us-east-1, us-west-2, etc.",
+			"are us-east-1, us-west-2, etc. Required by the `data_api` backend.",
 
 		"access_key": "The access key for API operations. You can retrieve this\n" +
 			"from the 'Security & Credentials' section of the AWS console.",
@@ -240,6 +283,35 @@ func init() {
 		"assume_role_policy": "The permissions applied when assuming a role. You cannot use," +
 			" this policy to grant further permissions that are in excess to those of the, " +
 			" role that is being assumed.",
+
+		"resource_arn": "The ARN of the Aurora Serverless DB cluster used by resources\n" +
+			"that do not set their own `resource_arn`.",
+
+		"secret_arn": "The ARN of the Secrets Manager secret holding the cluster credentials,\n" +
+			"used by resources that do not set their own `secret_arn`.",
+
+		"database": "The database used by resources that do not set their own `database`.",
+
+		"resume_timeout": "How long to wait for an auto-paused Aurora Serverless cluster\n" +
+			"to resume before failing, e.g. `5m`.",
+
+		"backend": "How statements are run: `data_api` through the RDS Data API, or\n" +
+			"`postgres` over a direct connection configured in the `postgres` block.",
+
+		"postgres_dsn": "A PostgreSQL connection URL or key=value connection string.\n" +
+			"Cannot be combined with `host`, `username` or `password`.",
+
+		"postgres_host": "The host of the PostgreSQL server.",
+
+		"postgres_port": "The port of the PostgreSQL server.",
+
+		"postgres_username": "The user to connect as.",
+
+		"postgres_password": "The password of the user to connect as.",
+
+		"postgres_database": "The database to connect to when a resource sets none.",
+
+		"postgres_sslmode": "The lib/pq sslmode, e.g. `require` or `verify-full`.",
 	}
 
 	endpointServiceNames = []string{
@@ -342,6 +414,7 @@ func init() {
 		"r53",
 		"ram",
 		"rds",
+		"rdsdataservice",
 		"redshift",
 		"resourcegroups",
 		"route53",
@@ -390,9 +463,35 @@ func providerConfigure(d *schema.ResourceData, terraformVersion string) (interfa
 		SkipRequestingAccountId: d.Get("skip_requesting_account_id").(bool),
 		SkipMetadataApiCheck:    d.Get("skip_metadata_api_check").(bool),
 		S3ForcePathStyle:        d.Get("s3_force_path_style").(bool),
+		ResourceArn:             d.Get("resource_arn").(string),
+		SecretArn:               d.Get("secret_arn").(string),
+		Database:                d.Get("database").(string),
 		terraformVersion:        terraformVersion,
 	}
 
+	resumeTimeout, err := time.ParseDuration(d.Get("resume_timeout").(string))
+	if err != nil {
+		return nil, err
+	}
+	config.ResumeTimeout = resumeTimeout
+
+	config.Backend = d.Get("backend").(string)
+	if config.Backend == backendPostgres {
+		postgresList := d.Get("postgres").([]interface{})
+		if len(postgresList) == 0 || postgresList[0] == nil {
+			return nil, fmt.Errorf("a postgres block is required when backend is %q", backendPostgres)
+		}
+		postgres := postgresList[0].(map[string]interface{})
+		dsn, err := postgresDSN(postgres)
+		if err != nil {
+			return nil, err
+		}
+		config.PostgresDSN = dsn
+		if config.Database == "" && postgres["dsn"].(string) == "" {
+			config.Database = postgres["database"].(string)
+		}
+	}
+
 	// Set CredsFilename, expanding home directory
 	credsPath, err := homedir.Expand(d.Get("shared_credentials_file").(string))
 	if err != nil {
@@ -453,6 +552,14 @@ func providerConfigure(d *schema.ResourceData, terraformVersion string) (interfa
 	return config.Client()
 }
 
+// validateDuration checks that v parses with time.ParseDuration.
+func validateDuration(v interface{}, k string) (ws []string, errors []error) {
+	if _, err := time.ParseDuration(v.(string)); err != nil {
+		errors = append(errors, fmt.Errorf("%q must be a duration such as \"30s\" or \"5m\": %s", k, err))
+	}
+	return
+}
+
 // This is a global MutexKV for use within this plugin.
 var awsMutexKV = mutexkv.NewMutexKV()
 
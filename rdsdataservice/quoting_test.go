@@ -0,0 +1,293 @@
+package rdsdataservice
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+var (
+	testQuotedIdentifierRegexp = regexp.MustCompile(`"((?:[^"]|"")*)"`)
+	testQuotedLiteralRegexp    = regexp.MustCompile(`(E?)'((?:[^']|'')*)'`)
+)
+
+// testIdentifiers returns the identifiers quoted in sql, unquoted the way the
+// PostgreSQL lexer would.
+func testIdentifiers(sql string) []string {
+	var identifiers []string
+	for _, match := range testQuotedIdentifierRegexp.FindAllStringSubmatch(sql, -1) {
+		identifiers = append(identifiers, strings.Replace(match[1], `""`, `"`, -1))
+	}
+	return identifiers
+}
+
+// testLiterals returns the string literals quoted in sql, unquoted the way
+// the PostgreSQL lexer would.
+func testLiterals(sql string) []string {
+	var literals []string
+	for _, match := range testQuotedLiteralRegexp.FindAllStringSubmatch(sql, -1) {
+		literal := strings.Replace(match[2], `''`, `'`, -1)
+		if match[1] == "E" {
+			literal = strings.Replace(literal, `\\`, `\`, -1)
+		}
+		literals = append(literals, literal)
+	}
+	return literals
+}
+
+// testRoundTripName reports whether name can be stored by PostgreSQL at all.
+func testRoundTripName(name string) bool {
+	return name != "" && strings.IndexByte(name, 0) == -1
+}
+
+// testFirstDDL returns the first statement of inputs that is not a catalog
+// query, which binds its values as parameters instead of quoting them.
+func testFirstDDL(inputs []*rdsdataservice.ExecuteStatementInput) string {
+	for _, input := range inputs {
+		if !aws.BoolValue(input.IncludeResultMetadata) {
+			return aws.StringValue(input.Sql)
+		}
+	}
+	return ""
+}
+
+type testNameRoundTrip struct {
+	resource    *schema.Resource
+	config      func(name string) map[string]interface{}
+	readMatch   string
+	readColumns []string
+	readRecord  func(name string) []*rdsdataservice.Field
+}
+
+// check creates, reads and deletes a resource called name through the fake
+// executor and verifies that name survives every step unchanged.
+func (rt testNameRoundTrip) check(t *testing.T, name string) bool {
+	if !testRoundTripName(name) {
+		return true
+	}
+
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	d := schema.TestResourceDataRaw(t, rt.resource.Schema, rt.config(name))
+
+	// Terraform normalizes configuration strings to Unicode NFC before the
+	// provider sees them, so that is the form that has to round-trip.
+	name = d.Get("name").(string)
+
+	if err := rt.resource.Create(d, client); err != nil {
+		t.Logf("create %q: %s", name, err)
+		return false
+	}
+	if got := testIdentifiers(aws.StringValue(executor.inputs[0].Sql)); len(got) == 0 || got[0] != name {
+		t.Logf("create %q: statement %q quotes %q", name, aws.StringValue(executor.inputs[0].Sql), got)
+		return false
+	}
+
+	executor.inputs = nil
+	executor.onRows(rt.readMatch, rt.readColumns, rt.readRecord(name))
+	if err := rt.resource.Read(d, client); err != nil {
+		t.Logf("read %q: %s", name, err)
+		return false
+	}
+	for _, parameter := range executor.inputs[0].Parameters {
+		if aws.StringValue(parameter.Name) == "name" && aws.StringValue(parameter.Value.StringValue) != name {
+			t.Logf("read %q: bound %q", name, aws.StringValue(parameter.Value.StringValue))
+			return false
+		}
+	}
+	if got := d.Get("name").(string); got != name {
+		t.Logf("read %q: state has %q", name, got)
+		return false
+	}
+
+	executor.inputs = nil
+	executor.onRows("SELECT current_user", []string{"name"}, testRecord(testStringField("postgres")))
+	if err := rt.resource.Delete(d, client); err != nil {
+		t.Logf("delete %q: %s", name, err)
+		return false
+	}
+	sql := testFirstDDL(executor.inputs)
+	if got := testIdentifiers(sql); len(got) == 0 || got[0] != name {
+		t.Logf("delete %q: statement %q quotes %q", name, sql, got)
+		return false
+	}
+
+	return true
+}
+
+func TestPostgresDatabaseNameRoundTrip(t *testing.T) {
+	rt := testNameRoundTrip{
+		resource: resourceAwsRdsdataservicePostgresDatabase(),
+		config: func(name string) map[string]interface{} {
+			config := testPostgresDatabaseConfig(name, "app_owner")
+			config["deletion_protection"] = false
+			return config
+		},
+		readMatch:   "from pg_database d",
+		readColumns: []string{"datname", "owner"},
+		readRecord: func(name string) []*rdsdataservice.Field {
+			return testRecord(testStringField(name), testStringField("app_owner"))
+		},
+	}
+
+	check := func(name string) bool { return rt.check(t, name) }
+	if err := quick.Check(check, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresSchemaNameRoundTrip(t *testing.T) {
+	rt := testNameRoundTrip{
+		resource: resourceAwsRdsdataservicePostgresSchema(),
+		config: func(name string) map[string]interface{} {
+			return testPostgresSchemaConfig(name, "app_owner")
+		},
+		readMatch:   "FROM pg_namespace",
+		readColumns: []string{"schema_name", "schema_owner"},
+		readRecord: func(name string) []*rdsdataservice.Field {
+			return testRecord(testStringField(name), testStringField("app_owner"))
+		},
+	}
+
+	check := func(name string) bool { return rt.check(t, name) }
+	if err := quick.Check(check, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresRoleNameRoundTrip(t *testing.T) {
+	rt := testNameRoundTrip{
+		resource: resourceAwsRdsdataservicePostgresRole(),
+		config: func(name string) map[string]interface{} {
+			return testPostgresRoleConfig(name, true)
+		},
+		readMatch:   "pg_catalog.pg_roles",
+		readColumns: testRoleColumns,
+		readRecord: func(name string) []*rdsdataservice.Field {
+			return testRecord(
+				testStringField(name),
+				testBoolField(false),
+				testBoolField(true),
+				testBoolField(false),
+				testBoolField(false),
+				testBoolField(true),
+				testBoolField(false),
+				testBoolField(false),
+				testLongField(-1),
+				testStringField("infinity"),
+			)
+		},
+	}
+
+	check := func(name string) bool { return rt.check(t, name) }
+	if err := quick.Check(check, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresRolePasswordRoundTrip(t *testing.T) {
+	check := func(password string) bool {
+		if !testRoundTripName(password) {
+			return true
+		}
+
+		executor := &fakeExecutor{}
+		config := testPostgresRoleConfig("app", true)
+		config["password"] = password
+		d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, config)
+		password = d.Get("password").(string)
+
+		if err := resourceAwsRdsdataservicePostgresRoleCreate(d, &AWSClient{executor: executor}); err != nil {
+			t.Logf("create: %s", err)
+			return false
+		}
+
+		sql := aws.StringValue(executor.inputs[0].Sql)
+		if got := testLiterals(sql); len(got) != 1 || got[0] != password {
+			t.Logf("password %q: statement %q quotes %q", password, sql, got)
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(check, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPostgresNameRoundTripExamples(t *testing.T) {
+	rt := testNameRoundTrip{
+		resource: resourceAwsRdsdataservicePostgresDatabase(),
+		config: func(name string) map[string]interface{} {
+			config := testPostgresDatabaseConfig(name, "app_owner")
+			config["deletion_protection"] = false
+			return config
+		},
+		readMatch:   "from pg_database d",
+		readColumns: []string{"datname", "owner"},
+		readRecord: func(name string) []*rdsdataservice.Field {
+			return testRecord(testStringField(name), testStringField("app_owner"))
+		},
+	}
+
+	for _, name := range []string{"MixedCase", "with-hyphen", `with"quote`, "it's", "two words", "semi;colon", `back\slash`} {
+		if !rt.check(t, name) {
+			t.Errorf("name %q did not round-trip", name)
+		}
+	}
+}
+
+func TestPostgresNamesRejectNUL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resource *schema.Resource
+		config   map[string]interface{}
+	}{
+		{
+			name:     "database name",
+			resource: resourceAwsRdsdataservicePostgresDatabase(),
+			config:   map[string]interface{}{"name": "app\x00_old"},
+		},
+		{
+			name:     "database settings",
+			resource: resourceAwsRdsdataservicePostgresDatabase(),
+			config:   map[string]interface{}{"name": "app", "settings": map[string]interface{}{"search_path": "app\x00"}},
+		},
+		{
+			name:     "schema owner",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			config:   map[string]interface{}{"name": "reporting", "owner": "app\x00"},
+		},
+		{
+			name:     "role password",
+			resource: resourceAwsRdsdataservicePostgresRole(),
+			config:   map[string]interface{}{"name": "app", "password": "secret\x00"},
+		},
+		{
+			name:     "grant objects",
+			resource: resourceAwsRdsdataservicePostgresGrant(),
+			config: map[string]interface{}{
+				"role":        "app",
+				"schema":      "public",
+				"object_type": "table",
+				"objects":     []interface{}{"orders\x00"},
+				"privileges":  []interface{}{"SELECT"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, errs := testCase.resource.Validate(terraform.NewResourceConfigRaw(testCase.config))
+			if len(errs) != 1 || !strings.Contains(errs[0].Error(), "cannot contain a NUL byte") {
+				t.Fatalf("expected a NUL byte error, got %v", errs)
+			}
+		})
+	}
+}
@@ -3,11 +3,14 @@ package rdsdataservice
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
 
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func resourceAwsRdsdataservicePostgresDatabase() *schema.Resource {
@@ -18,51 +21,147 @@ func resourceAwsRdsdataservicePostgresDatabase() *schema.Resource {
 		Delete: resourceAwsRdsdataservicePostgresDatabaseDelete,
 		Exists: resourceAwsRdsdataservicePostgresDatabaseExists,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceAwsRdsdataservicePostgresDatabaseImport,
+		},
+		CustomizeDiff: customizeDiffDataAPITarget(false),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
 			"name": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Database name.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "Database name.",
 			},
 			"resource_arn": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "DB ARN.",
+				Optional:    true,
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
 			},
 			"secret_arn": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "DBA Secret ARN.",
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
 			},
 			"owner": {
-				Type:        schema.TypeString,
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "postgres",
+				ValidateFunc: validateNoNUL,
+				Description:  "The ROLE which owns the database.",
+			},
+			"template": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressImportedDatabaseTemplate,
+				ValidateFunc:     validateNoNUL,
+				Description:      "The database to create the database from. Defaults to template1.",
+			},
+			"encoding": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressEquivalentDatabaseEncoding,
+				ValidateFunc:     validateNoNUL,
+				Description:      "The character set encoding of the database. Defaults to the encoding of the template.",
+			},
+			"lc_collate": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The collation order (LC_COLLATE) of the database. Defaults to the collation of the template.",
+			},
+			"lc_ctype": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The character classification (LC_CTYPE) of the database. Defaults to the classification of the template.",
+			},
+			"connection_limit": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      -1,
+				ValidateFunc: validation.IntAtLeast(-1),
+				Description:  "How many concurrent connections can be made to the database. -1 means no limit.",
+			},
+			"allow_connections": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Whether the database accepts connections.",
+			},
+			"is_template": {
+				Type:        schema.TypeBool,
 				Optional:    true,
-				Default:     "postgres",
-				Description: "The ROLE which owns the database.",
+				Default:     false,
+				Description: "Whether the database can be cloned by any user with CREATEDB privileges.",
+			},
+			"settings": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				ValidateFunc: validateNoNUL,
+				Description:  "The configuration parameters set for every role in the database, e.g. search_path.",
+			},
+			"force_drop": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to terminate the connections to the database before dropping it.",
+			},
+			"imported": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the database was imported rather than created, in which case its template is unknown.",
+			},
+			"deletion_protection": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Description: "Whether to refuse to drop the database. If unset, databases holding user tables are not dropped.",
 			},
 		},
 	}
 }
 
 func resourceAwsRdsdataservicePostgresDatabaseCreate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
 
-	sql := fmt.Sprintf("CREATE DATABASE %s OWNER %s;",
-		d.Get("name").(string),
-		d.Get("owner").(string))
-
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	options := []string{pgsql.Format("OWNER %I", d.Get("owner").(string))}
+	if v := d.Get("template").(string); v != "" {
+		options = append(options, pgsql.Format("TEMPLATE %I", v))
 	}
+	for _, setting := range []struct{ attribute, option string }{
+		{"encoding", "ENCODING"},
+		{"lc_collate", "LC_COLLATE"},
+		{"lc_ctype", "LC_CTYPE"},
+	} {
+		if v := d.Get(setting.attribute).(string); v != "" {
+			options = append(options, pgsql.Format(setting.option+" %L", v))
+		}
+	}
+	options = append(options, databaseOptions(d, false)...)
+
+	sql := pgsql.Format("CREATE DATABASE %I ", d.Get("name").(string)) + strings.Join(options, " ") + ";"
+
+	createOpts := target.statement(sql)
 
 	log.Printf("[DEBUG] Create Postgres Database: %#v", createOpts)
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutCreate), func() (bool, error) {
+		return dbExists(d.Get("name").(string), target, meta)
+	})
 
 	if err != nil {
 		return fmt.Errorf("Error creating Postgres Database: %#v", err)
@@ -71,24 +170,66 @@ func resourceAwsRdsdataservicePostgresDatabaseCreate(d *schema.ResourceData, met
 	d.SetId(d.Get("name").(string))
 	log.Printf("[INFO] Postgres Database ID: %s", d.Id())
 
-	return err
+	// CREATE DATABASE cannot be rolled back, and failing Create would taint
+	// the database and replace it. Settings that fail, all of them as they
+	// run in one transaction, are left out of state instead, so the next
+	// plan shows them and Update sets them or reports the error.
+	if err := alterDatabaseSettings(d, target, meta, nil, d.Get("settings").(map[string]interface{}), d.Timeout(schema.TimeoutCreate)); err != nil {
+		log.Printf("[WARN] Error setting Postgres Database %s settings, leaving them out of state: %#v", d.Id(), err)
+		d.Set("settings", map[string]interface{}{})
+	}
+
+	return nil
 }
 
 func resourceAwsRdsdataservicePostgresDatabaseDelete(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
 
-	sql := fmt.Sprintf("DROP DATABASE %s;",
+	if err := checkDatabaseDeletionProtection(d, target, meta); err != nil {
+		return err
+	}
+
+	// Template databases cannot be dropped
+	if d.Get("is_template").(bool) {
+		alterOpts := target.statement(pgsql.Format("ALTER DATABASE %I WITH IS_TEMPLATE false", d.Get("name").(string)))
+
+		log.Printf("[DEBUG] Unmark Postgres Database as template: %#v", alterOpts)
+
+		err := executeDDL(executor, &alterOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+			database, err := readDatabase(d.Get("name").(string), target, meta)
+			return database == nil || !database.getBool("datistemplate"), err
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error dropping Postgres Database: %#v", err)
+		}
+	}
+
+	sql := pgsql.Format("DROP DATABASE %I;",
 		d.Get("name").(string))
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	if d.Get("force_drop").(bool) {
+		version, err := serverVersionNum(target, meta)
+		if err != nil {
+			return fmt.Errorf("Error dropping Postgres Database: %s", err)
+		}
+
+		if version >= 130000 {
+			sql = pgsql.Format("DROP DATABASE %I WITH (FORCE);", d.Get("name").(string))
+		} else if err := terminateDatabaseConnections(d, target, meta); err != nil {
+			return fmt.Errorf("Error dropping Postgres Database: %#v", err)
+		}
 	}
 
+	createOpts := target.statement(sql)
+
 	log.Printf("[DEBUG] Drop Postgres Database: %#v", createOpts)
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		exists, err := dbExists(d.Get("name").(string), target, meta)
+		return !exists, err
+	})
 
 	if err != nil {
 		return fmt.Errorf("Error dropping Postgres Database: %#v", err)
@@ -98,92 +239,149 @@ func resourceAwsRdsdataservicePostgresDatabaseDelete(d *schema.ResourceData, met
 	return err
 }
 
-func resourceAwsRdsdataservicePostgresDatabaseExists(d *schema.ResourceData, meta interface{}) (bool, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+// checkDatabaseDeletionProtection refuses to drop a database that has
+// deletion_protection set or, unless it is explicitly disabled, that still
+// holds user tables.
+func checkDatabaseDeletionProtection(d *schema.ResourceData, target dataAPITarget, meta interface{}) error {
+	name := d.Get("name").(string)
 
-	sql := fmt.Sprintf("SELECT datname FROM pg_database WHERE datname='%s';",
-		d.Get("name").(string))
-
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	protection, set := d.GetOkExists("deletion_protection")
+	if set && protection.(bool) {
+		return fmt.Errorf("Postgres Database %s has deletion_protection enabled, set it to false and apply before destroying it", name)
+	}
+	if set {
+		return nil
 	}
 
-	log.Printf("[DEBUG] Check Postgres Database exists: %#v", createOpts)
+	database, err := readDatabase(name, target, meta)
+	if err != nil {
+		return fmt.Errorf("Error dropping Postgres Database: %#v", err)
+	}
+	if database == nil {
+		return nil
+	}
+	if !database.getBool("datallowconn") {
+		return fmt.Errorf("Postgres Database %s does not allow connections, so it cannot be checked for user tables; set deletion_protection to false and apply before destroying it", name)
+	}
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	rows, err := queryRows(meta, target.withDatabase(name), `
+SELECT count(*) AS tables
+FROM pg_class
+JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+WHERE pg_class.relkind IN ('r', 'p')
+    AND pg_namespace.nspname NOT IN ('pg_catalog', 'information_schema')
+    AND pg_namespace.nspname NOT LIKE 'pg\_toast%'`)
 
 	if err != nil {
-		return false, fmt.Errorf("Error checking Postgres Database exists: %#v", err)
+		return fmt.Errorf("Error checking Postgres Database %s for user tables: %#v", name, err)
 	}
 
-	if len(output.Records) != 1 {
-		return false, nil
+	if len(rows) == 1 && rows[0].getInt64("tables") > 0 {
+		return fmt.Errorf("Postgres Database %s still contains %d user tables; set deletion_protection to false and apply before destroying it", name, rows[0].getInt64("tables"))
 	}
 
-	return true, nil
+	return nil
 }
 
-func resourceAwsRdsdataservicePostgresDatabaseRead(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+// terminateDatabaseConnections stops new connections to the database and
+// terminates the existing ones, so that it can be dropped on PostgreSQL
+// versions before 13, which lack DROP DATABASE ... WITH (FORCE).
+func terminateDatabaseConnections(d *schema.ResourceData, target dataAPITarget, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	name := d.Get("name").(string)
 
-	sql := fmt.Sprintf("SELECT d.datname, pg_catalog.pg_get_userbyid(d.datdba) from pg_database d WHERE datname='%s';",
-		d.Get("name").(string))
+	alterOpts := target.statement(pgsql.Format("ALTER DATABASE %I WITH ALLOW_CONNECTIONS false", name))
+
+	log.Printf("[DEBUG] Disallow connections to Postgres Database: %#v", alterOpts)
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	err := executeDDL(executor, &alterOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		database, err := readDatabase(name, target, meta)
+		return database == nil || !database.getBool("datallowconn"), err
+	})
+
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[DEBUG] Read Postgres Database: %#v", createOpts)
+	terminateOpts := target.statement("SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = :name AND pid <> pg_backend_pid()")
+	terminateOpts.Parameters = []*rdsdataservice.SqlParameter{stringParameter("name", name)}
+
+	log.Printf("[DEBUG] Terminate connections to Postgres Database: %#v", terminateOpts)
+
+	_, err = executor.ExecuteStatement(&terminateOpts)
+	return err
+}
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+func resourceAwsRdsdataservicePostgresDatabaseExists(d *schema.ResourceData, meta interface{}) (bool, error) {
+	output, err := queryCatalog(meta, resourceTarget(d, meta),
+		"SELECT datname FROM pg_database WHERE datname = :name;",
+		stringParameter("name", d.Get("name").(string)))
 
 	if err != nil {
-		return fmt.Errorf("Error reading Postgres Database: %#v", err)
+		return false, fmt.Errorf("Error checking Postgres Database exists: %#v", err)
 	}
 
 	if len(output.Records) != 1 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func resourceAwsRdsdataservicePostgresDatabaseRead(d *schema.ResourceData, meta interface{}) error {
+	database, err := readDatabase(d.Get("name").(string), resourceTarget(d, meta), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Database: %#v", err)
+	}
+
+	if database == nil {
 		d.SetId("")
 		return nil
 	}
 
-	log.Printf("[DEBUG] Read Postgres Database details: %#v", output)
+	log.Printf("[DEBUG] Read Postgres Database details: %#v", database)
 
-	/*
-		sqlFmt := `SELECT %s` +
-			`FROM pg_catalog.pg_database AS d, pg_catalog.pg_tablespace AS ts ` +
-			`WHERE d.datname = '%s' AND d.dattablespace = ts.oid`
-		sql = fmt.Sprintf(sqlFmt, d.Get("name").(string))
+	d.Set("name", database.getString("datname"))
+	d.Set("owner", database.getString("owner"))
+	d.Set("encoding", database.getString("encoding"))
+	d.Set("lc_collate", database.getString("datcollate"))
+	d.Set("lc_ctype", database.getString("datctype"))
+	d.Set("connection_limit", database.getInt64("datconnlimit"))
+	d.Set("allow_connections", database.getBool("datallowconn"))
+	d.Set("is_template", database.getBool("datistemplate"))
 
-		createOpts = rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
-		}
-		log.Printf("[DEBUG] Read Postgres Database details: %#v", createOpts)
+	settings, err := readSettings("", database.getString("datname"), resourceTarget(d, meta), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Database: %s", err)
+	}
+	d.Set("settings", settingsState(d.Get("settings").(map[string]interface{}), settings))
 
-		if err != nil {
-			return fmt.Errorf("Error reading Postgres Database: %#v", err)
-		}
+	return nil
+}
 
-		if len(output.Records) != 1 {
-			d.SetId("")
-			return nil
-		}
+// readDatabase returns the pg_database row of the database name, or nil if
+// it does not exist.
+func readDatabase(name string, target dataAPITarget, meta interface{}) (resultRow, error) {
+	rows, err := queryRows(meta, target,
+		"SELECT d.datname, pg_catalog.pg_get_userbyid(d.datdba) AS owner, pg_catalog.pg_encoding_to_char(d.encoding) AS encoding, "+
+			"d.datcollate, d.datctype, d.datconnlimit, d.datallowconn, d.datistemplate "+
+			"from pg_database d WHERE datname = :name;",
+		stringParameter("name", name))
 
-		log.Printf("[DEBUG] Read Postgres Database details: %#v", output.Records)
-	*/
-	d.Set("name", output.Records[0][0].StringValue)
-	d.Set("owner", output.Records[0][1].StringValue)
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	if len(rows) != 1 {
+		return nil, nil
+	}
+
+	return rows[0], nil
 }
 
 func resourceAwsRdsdataservicePostgresDatabaseUpdate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
 
 	if d.HasChange("name") {
 		oraw, nraw := d.GetChange("name")
@@ -193,17 +391,15 @@ func resourceAwsRdsdataservicePostgresDatabaseUpdate(d *schema.ResourceData, met
 			return fmt.Errorf("Error setting database name to an empty string")
 		}
 
-		sql := fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", o, n)
+		sql := pgsql.Format("ALTER DATABASE %I RENAME TO %I", o, n)
 
-		createOpts := rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
-		}
+		createOpts := target.statement(sql)
 
 		log.Printf("[DEBUG] Update Postgres Database name: %#v", createOpts)
 
-		_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			return dbExists(n, target, meta)
+		})
 
 		if err != nil {
 			return fmt.Errorf("Error updating Postgres Database name: %#v", err)
@@ -212,49 +408,118 @@ func resourceAwsRdsdataservicePostgresDatabaseUpdate(d *schema.ResourceData, met
 	}
 
 	if d.HasChange("owner") {
-		oraw, nraw := d.GetChange("owner")
-		o := oraw.(string)
-		n := nraw.(string)
+		n := d.Get("owner").(string)
 		if n == "" {
 			return fmt.Errorf("Error setting database owner to an empty string")
 		}
 
-		sql := fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", o, n)
+		sql := pgsql.Format("ALTER DATABASE %I OWNER TO %I", d.Get("name").(string), n)
 
-		createOpts := rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
-		}
+		createOpts := target.statement(sql)
 
 		log.Printf("[DEBUG] Update Postgres Database owner: %#v", createOpts)
 
-		_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			return dbOwnedBy(d.Get("name").(string), n, target, meta)
+		})
 
 		if err != nil {
 			return fmt.Errorf("Error updating Postgres Database owner: %#v", err)
 		}
 	}
 
+	if options := databaseOptions(d, true); len(options) > 0 {
+		sql := pgsql.Format("ALTER DATABASE %I WITH ", d.Get("name").(string)) + strings.Join(options, " ")
+
+		createOpts := target.statement(sql)
+
+		log.Printf("[DEBUG] Update Postgres Database: %#v", createOpts)
+
+		err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			database, err := readDatabase(d.Get("name").(string), target, meta)
+			return database != nil &&
+				database.getInt64("datconnlimit") == int64(d.Get("connection_limit").(int)) &&
+				database.getBool("datallowconn") == d.Get("allow_connections").(bool) &&
+				database.getBool("datistemplate") == d.Get("is_template").(bool), err
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error updating Postgres Database: %#v", err)
+		}
+	}
+
+	if d.HasChange("settings") {
+		o, n := d.GetChange("settings")
+		if err := alterDatabaseSettings(d, target, meta, o.(map[string]interface{}), n.(map[string]interface{}), d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return fmt.Errorf("Error updating Postgres Database settings: %#v", err)
+		}
+	}
+
 	return nil
 }
 
-func rdsDataserviceExecuteStatement(d *schema.ResourceData, sql string, meta interface{}) (*rdsdataservice.ExecuteStatementOutput, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+// alterDatabaseSettings changes the settings of the database from old to
+// new in a transaction. Unlike CREATE DATABASE, ALTER DATABASE ... SET can
+// run in one.
+func alterDatabaseSettings(d *schema.ResourceData, target dataAPITarget, meta interface{}, old, new map[string]interface{}, timeout time.Duration) error {
+	executor := meta.(*AWSClient).executor
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	statements := settingsStatements(pgsql.Format("ALTER DATABASE %I", d.Get("name").(string)), old, new)
+	if len(statements) == 0 {
+		return nil
 	}
 
-	log.Printf("[DEBUG] Update Postgres Database name: %#v", createOpts)
+	return withTransaction(executor, target, func(transactionID *string) error {
+		return alterSettings(executor, target, transactionID, statements, timeout)
+	})
+}
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+// databaseOptions returns the options of a CREATE DATABASE or ALTER
+// DATABASE ... WITH statement setting the attributes of d that can be
+// changed in place. When changedOnly is set only changed attributes are
+// included; otherwise only those differing from the PostgreSQL defaults.
+func databaseOptions(d *schema.ResourceData, changedOnly bool) []string {
+	var options []string
 
-	if err != nil {
-		return nil, fmt.Errorf("Error updating Postgres Database name: %#v", err)
+	if limit := d.Get("connection_limit").(int); changedOnly && d.HasChange("connection_limit") || !changedOnly && limit != -1 {
+		options = append(options, fmt.Sprintf("CONNECTION LIMIT %d", limit))
+	}
+	if allow := d.Get("allow_connections").(bool); changedOnly && d.HasChange("allow_connections") || !changedOnly && !allow {
+		options = append(options, fmt.Sprintf("ALLOW_CONNECTIONS %t", allow))
 	}
+	if template := d.Get("is_template").(bool); changedOnly && d.HasChange("is_template") || !changedOnly && template {
+		options = append(options, fmt.Sprintf("IS_TEMPLATE %t", template))
+	}
+
+	return options
+}
+
+// resourceAwsRdsdataservicePostgresDatabaseImport imports a database like
+// any other resource, marking it as imported for
+// suppressImportedDatabaseTemplate. Create never sets the marker.
+func resourceAwsRdsdataservicePostgresDatabaseImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	d.Set("imported", true)
+
+	return dataAPIImport{
+		resourceType: "rdsdataservice_postgres_database",
+		database:     false,
+		names:        []string{"name"},
+	}.state(d, meta)
+}
+
+// suppressImportedDatabaseTemplate ignores the template of an imported
+// database that has none in state. PostgreSQL does not record the template
+// a database was created from, so imported databases never have one, and
+// setting it must not replace them. A database created without a template
+// is still replaced when one is added.
+func suppressImportedDatabaseTemplate(k, old, new string, d *schema.ResourceData) bool {
+	return old == "" && d.Get("imported").(bool)
+}
 
-	return output, nil
+// suppressEquivalentDatabaseEncoding ignores differences in case and
+// punctuation between encoding names, as PostgreSQL does: utf-8 and UTF8
+// name the same encoding.
+func suppressEquivalentDatabaseEncoding(k, old, new string, d *schema.ResourceData) bool {
+	normalize := strings.NewReplacer("-", "", "_", "")
+	return strings.EqualFold(normalize.Replace(old), normalize.Replace(new))
 }
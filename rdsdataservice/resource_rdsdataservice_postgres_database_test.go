@@ -0,0 +1,494 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func testPostgresDatabaseConfig(name, owner string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         name,
+		"owner":        owner,
+		"resource_arn": testResourceArn,
+		"secret_arn":   testSecretArn,
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseCreate(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testPostgresDatabaseConfig("app", "app_owner"))
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `CREATE DATABASE "app" OWNER "app_owner";`)
+	if d.Id() != "app" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseCreateOptions(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresDatabaseConfig("tenant", "app_owner")
+	config["template"] = "template0"
+	config["encoding"] = "UTF8"
+	config["lc_collate"] = "en_US.UTF-8"
+	config["lc_ctype"] = "en_US.UTF-8"
+	config["connection_limit"] = 20
+	config["allow_connections"] = false
+	config["is_template"] = true
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `CREATE DATABASE "tenant" OWNER "app_owner" TEMPLATE "template0" ENCODING 'UTF8' LC_COLLATE 'en_US.UTF-8' LC_CTYPE 'en_US.UTF-8' `+
+		`CONNECTION LIMIT 20 ALLOW_CONNECTIONS false IS_TEMPLATE true;`)
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseCreateContinuesAfterTimeout(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		failOnceOn("CREATE DATABASE", awserr.New(rdsdataservice.ErrCodeStatementTimeoutException, "Request timed out", nil)).
+		on("FROM pg_database", testRecord(testStringField("app")))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testPostgresDatabaseConfig("app", "app_owner"))
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		`CREATE DATABASE "app" OWNER "app_owner";`,
+		"SELECT datname FROM pg_database WHERE datname = :name",
+	)
+	if !aws.BoolValue(executor.inputs[0].ContinueAfterTimeout) {
+		t.Fatal("expected ContinueAfterTimeout to be set")
+	}
+	if d.Id() != "app" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseRead(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("from pg_database d", []string{"datname", "owner", "encoding", "datcollate", "datctype", "datconnlimit", "datallowconn", "datistemplate"},
+			testRecord(testStringField("app"), testStringField("new_owner"), testStringField("UTF8"), testStringField("en_US.UTF-8"),
+				testStringField("C"), testLongField(10), testBoolField(false), testBoolField(true)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testPostgresDatabaseConfig("app", "app_owner"))
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The database is read first, then its settings
+	executor.statements = executor.statements[:1]
+	executor.expectStatements(t, "SELECT d.datname, pg_catalog.pg_get_userbyid(d.datdba) AS owner, pg_catalog.pg_encoding_to_char(d.encoding) AS encoding, "+
+		"d.datcollate, d.datctype, d.datconnlimit, d.datallowconn, d.datistemplate from pg_database d WHERE datname = :name;")
+	executor.expectParameters(t, 0, map[string]string{"name": "app"})
+	expected := map[string]interface{}{
+		"owner":             "new_owner",
+		"encoding":          "UTF8",
+		"lc_collate":        "en_US.UTF-8",
+		"lc_ctype":          "C",
+		"connection_limit":  10,
+		"allow_connections": false,
+		"is_template":       true,
+	}
+	for attribute, want := range expected {
+		if got := d.Get(attribute); got != want {
+			t.Errorf("%s: got %v, expected %v", attribute, got, want)
+		}
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseReadNotFound(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testPostgresDatabaseConfig("app", "app_owner"))
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "" {
+		t.Fatalf("expected resource to be removed from state, got ID %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseExistsQuotedName(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testPostgresDatabaseConfig("app' OR '1'='1", "app_owner"))
+	d.SetId("app")
+
+	if _, err := resourceAwsRdsdataservicePostgresDatabaseExists(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, "SELECT datname FROM pg_database WHERE datname = :name;")
+	executor.expectParameters(t, 0, map[string]string{"name": "app' OR '1'='1"})
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseUpdate(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresDatabase(), "app",
+		testPostgresDatabaseConfig("app", "app_owner"),
+		testPostgresDatabaseConfig("app2", "new_owner"),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		`ALTER DATABASE "app" RENAME TO "app2"`,
+		`ALTER DATABASE "app2" OWNER TO "new_owner"`,
+	)
+	if d.Id() != "app2" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseUpdateOptions(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	updated := testPostgresDatabaseConfig("app", "app_owner")
+	updated["connection_limit"] = 5
+	updated["is_template"] = true
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresDatabase(), "app",
+		testPostgresDatabaseConfig("app", "app_owner"),
+		updated,
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `ALTER DATABASE "app" WITH CONNECTION LIMIT 5 IS_TEMPLATE true`)
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseSettings(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	old := testPostgresDatabaseConfig("app", "app_owner")
+	old["settings"] = map[string]interface{}{"statement_timeout": "30s", "work_mem": "64MB"}
+	new := testPostgresDatabaseConfig("app", "app_owner")
+	new["settings"] = map[string]interface{}{"statement_timeout": "60s", "search_path": `"$user", public, app`}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresDatabase(), "app", old, new, client)
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER DATABASE "app" RESET "work_mem"`,
+		`ALTER DATABASE "app" SET "search_path" = '$user', 'public', 'app'`,
+		`ALTER DATABASE "app" SET "statement_timeout" = '60s'`,
+		"COMMIT",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseCreateSettingsFail(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		failOn("SET", awserr.New(rdsdataservice.ErrCodeBadRequestException, `unrecognized configuration parameter "statment_timeout"`, nil))
+	config := testPostgresDatabaseConfig("app", "app_owner")
+	config["settings"] = map[string]interface{}{"statment_timeout": "30s"}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+
+	// Failing would taint, and so replace, the database that was created
+	if err := resourceAwsRdsdataservicePostgresDatabaseCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		`CREATE DATABASE "app" OWNER "app_owner";`,
+		"BEGIN",
+		`ALTER DATABASE "app" SET "statment_timeout" = '30s'`,
+		"ROLLBACK",
+	)
+	if d.Id() != "app" {
+		t.Fatalf("unexpected ID: %q", d.Id())
+	}
+	if got := d.Get("settings").(map[string]interface{}); len(got) != 0 {
+		t.Fatalf("expected the failed settings to be left out of state, got %v", got)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseReadSettings(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("from pg_database d", []string{"datname"}, testRecord(testStringField("app"))).
+		onRows("FROM pg_db_role_setting", []string{"setting"},
+			testRecord(testStringField(`search_path="$user", public`)),
+			testRecord(testStringField("statement_timeout=5min")))
+	config := testPostgresDatabaseConfig("app", "app_owner")
+	config["settings"] = map[string]interface{}{"search_path": `"$user",public`, "statement_timeout": "30s"}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectParameters(t, 1, map[string]string{"role": "", "database": "app"})
+	settings := d.Get("settings").(map[string]interface{})
+	// Equivalent list values keep their configured spelling
+	if v := settings["search_path"]; v != `"$user",public` {
+		t.Errorf("search_path: got %v", v)
+	}
+	if v := settings["statement_timeout"]; v != "5min" {
+		t.Errorf("statement_timeout: got %v", v)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseTemplateDiff(t *testing.T) {
+	testCases := []struct {
+		name        string
+		imported    bool
+		oldTemplate string
+		newTemplate string
+		requiresNew bool
+	}{
+		{"imported", true, "", "tenant_template", false},
+		{"created without template", false, "", "tenant_template", true},
+		{"changed", false, "template0", "tenant_template", true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := resourceAwsRdsdataservicePostgresDatabase()
+			old := testPostgresDatabaseConfig("tenant", "app_owner")
+			if testCase.oldTemplate != "" {
+				old["template"] = testCase.oldTemplate
+			}
+			state := schema.TestResourceDataRaw(t, r.Schema, old)
+			state.SetId("tenant")
+			state.Set("imported", testCase.imported)
+			new := testPostgresDatabaseConfig("tenant", "app_owner")
+			new["template"] = testCase.newTemplate
+
+			diff, err := r.Diff(state.State(), terraform.NewResourceConfigRaw(new), &AWSClient{})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if got := diff != nil && diff.RequiresNew(); got != testCase.requiresNew {
+				t.Fatalf("got RequiresNew %t, expected %t", got, testCase.requiresNew)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseImportMarker(t *testing.T) {
+	r := resourceAwsRdsdataservicePostgresDatabase()
+
+	d, err := testImportState(t, r, testResourceArn+"||tenant|"+testSecretArn, &AWSClient{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !d.Get("imported").(bool) {
+		t.Fatal("expected an imported database to be marked as imported")
+	}
+
+	created := schema.TestResourceDataRaw(t, r.Schema, testPostgresDatabaseConfig("tenant", "app_owner"))
+	if err := resourceAwsRdsdataservicePostgresDatabaseCreate(created, &AWSClient{executor: &fakeExecutor{}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if created.Get("imported").(bool) {
+		t.Fatal("expected a created database not to be marked as imported")
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseEncodingDiff(t *testing.T) {
+	testCases := []struct {
+		old, new string
+		suppress bool
+	}{
+		{"UTF8", "utf8", true},
+		{"UTF8", "UTF-8", true},
+		{"LATIN1", "UTF8", false},
+	}
+
+	for _, testCase := range testCases {
+		if got := suppressEquivalentDatabaseEncoding("encoding", testCase.old, testCase.new, nil); got != testCase.suppress {
+			t.Errorf("%s -> %s: got %t, expected %t", testCase.old, testCase.new, got, testCase.suppress)
+		}
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseDeleteTemplate(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresDatabaseConfig("app", "app_owner")
+	config["is_template"] = true
+	config["deletion_protection"] = false
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		`ALTER DATABASE "app" WITH IS_TEMPLATE false`,
+		`DROP DATABASE "app";`,
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseDelete(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresDatabaseConfig("app", "app_owner")
+	config["deletion_protection"] = false
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `DROP DATABASE "app";`)
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseDeletionProtection(t *testing.T) {
+	testCases := []struct {
+		name       string
+		protection interface{}
+		allowConn  bool
+		tables     int64
+		expectErr  string
+	}{
+		{name: "enabled", protection: true, allowConn: true, expectErr: "has deletion_protection enabled"},
+		{name: "disabled with tables", protection: false, allowConn: true, tables: 3},
+		{name: "unset without tables", allowConn: true},
+		{name: "unset with tables", allowConn: true, tables: 3, expectErr: "still contains 3 user tables"},
+		{name: "unset without connections", expectErr: "does not allow connections"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				onRows("from pg_database d", []string{"datname", "datallowconn", "datistemplate"},
+					testRecord(testStringField("app"), testBoolField(testCase.allowConn), testBoolField(false))).
+				onRows("FROM pg_class", []string{"tables"}, testRecord(testLongField(testCase.tables)))
+			config := testPostgresDatabaseConfig("app", "app_owner")
+			if testCase.protection != nil {
+				config["deletion_protection"] = testCase.protection
+			}
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+			d.SetId("app")
+
+			err := resourceAwsRdsdataservicePostgresDatabaseDelete(d, &AWSClient{executor: executor})
+
+			if testCase.expectErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				if last := executor.statements[len(executor.statements)-1]; last != `DROP DATABASE "app";` {
+					t.Fatalf("expected the database to be dropped, got %v", executor.statements)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.expectErr, err)
+			}
+			for _, sql := range executor.statements {
+				if strings.HasPrefix(sql, "DROP") {
+					t.Fatalf("unexpected statement %q", sql)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseDeletionProtectionTablesQuery(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("from pg_database d", []string{"datname", "datallowconn"}, testRecord(testStringField("app"), testBoolField(true)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testPostgresDatabaseConfig("app", "app_owner"))
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseDelete(d, &AWSClient{executor: executor, database: "postgres"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// User tables are counted in the database itself
+	if !strings.Contains(executor.statements[1], "FROM pg_class") {
+		t.Fatalf("unexpected statement %q", executor.statements[1])
+	}
+	if v := aws.StringValue(executor.inputs[1].Database); v != "app" {
+		t.Fatalf("tables were counted in database %q", v)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseDeletionProtectionState(t *testing.T) {
+	r := resourceAwsRdsdataservicePostgresDatabase()
+	d := r.Data(&terraform.InstanceState{
+		ID: "app",
+		Attributes: map[string]string{
+			"id":                  "app",
+			"name":                "app",
+			"deletion_protection": "false",
+		},
+	})
+	executor := (&fakeExecutor{}).
+		onRows("from pg_database d", []string{"datname", "datallowconn"}, testRecord(testStringField("app"), testBoolField(true))).
+		onRows("FROM pg_class", []string{"tables"}, testRecord(testLongField(3)))
+
+	if err := resourceAwsRdsdataservicePostgresDatabaseDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDatabaseForceDrop(t *testing.T) {
+	testCases := []struct {
+		name     string
+		version  int64
+		expected []string
+	}{
+		{
+			name:    "PostgreSQL 13",
+			version: 130004,
+			expected: []string{
+				"SELECT current_setting('server_version_num')::integer AS version",
+				`DROP DATABASE "app" WITH (FORCE);`,
+			},
+		},
+		{
+			name:    "PostgreSQL 11",
+			version: 110009,
+			expected: []string{
+				"SELECT current_setting('server_version_num')::integer AS version",
+				`ALTER DATABASE "app" WITH ALLOW_CONNECTIONS false`,
+				"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = :name AND pid <> pg_backend_pid()",
+				`DROP DATABASE "app";`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				onRows("server_version_num", []string{"version"}, testRecord(testLongField(testCase.version)))
+			config := testPostgresDatabaseConfig("app", "app_owner")
+			config["force_drop"] = true
+			config["deletion_protection"] = false
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, config)
+			d.SetId("app")
+
+			if err := resourceAwsRdsdataservicePostgresDatabaseDelete(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectStatements(t, testCase.expected...)
+			if testCase.version < 130000 {
+				executor.expectParameters(t, 2, map[string]string{"name": "app"})
+			}
+		})
+	}
+}
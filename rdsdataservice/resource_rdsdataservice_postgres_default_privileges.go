@@ -0,0 +1,321 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// defaultPrivilegesObjectType describes a kind of object default privileges
+// are set for. The privileges are those of the grantObjectTypes entry of
+// the same name.
+type defaultPrivilegesObjectType struct {
+	// keyword names the objects in ALTER DEFAULT PRIVILEGES.
+	keyword string
+	// objtype is the pg_default_acl.defaclobjtype of the objects.
+	objtype string
+}
+
+// defaultPrivilegesObjectTypes are the accepted values of object_type.
+var defaultPrivilegesObjectTypes = map[string]defaultPrivilegesObjectType{
+	"table":    {keyword: "TABLES", objtype: "r"},
+	"sequence": {keyword: "SEQUENCES", objtype: "S"},
+	"function": {keyword: "FUNCTIONS", objtype: "f"},
+	"type":     {keyword: "TYPES", objtype: "T"},
+	"schema":   {keyword: "SCHEMAS", objtype: "n"},
+}
+
+func resourceAwsRdsdataservicePostgresDefaultPrivileges() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsRdsdataservicePostgresDefaultPrivilegesCreate,
+		Read:   resourceAwsRdsdataservicePostgresDefaultPrivilegesRead,
+		Update: resourceAwsRdsdataservicePostgresDefaultPrivilegesUpdate,
+		Delete: resourceAwsRdsdataservicePostgresDefaultPrivilegesDelete,
+		Importer: dataAPIImport{
+			resourceType: "rdsdataservice_postgres_default_privileges",
+			database:     true,
+			names:        []string{"role", "owner", "schema", "object_type"},
+			// Defaults for every schema have no schema
+			optional: []string{"schema"},
+			id: func(d *schema.ResourceData, target dataAPITarget) string {
+				return generateDefaultPrivilegesID(d, target.Database)
+			},
+		}.importer(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffDataAPITarget(true),
+			customizeDiffDefaultPrivileges,
+		),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"role": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The role the privileges are granted to",
+			},
+			"owner": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The role whose new objects the privileges apply to",
+			},
+			"database": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The database the default privileges are set in. Defaults to the provider database",
+			},
+			"schema": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The schema whose new objects the privileges apply to. Defaults to every schema",
+			},
+			"object_type": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				ValidateFunc: func(v interface{}, k string) (ws []string, errors []error) {
+					if _, ok := defaultPrivilegesObjectTypes[normalizeGrantObjectType(v.(string))]; !ok {
+						errors = append(errors, fmt.Errorf("expected %s to be one of [function schema sequence table type], got %s", k, v))
+					}
+					return
+				},
+				StateFunc: func(v interface{}) string {
+					return normalizeGrantObjectType(v.(string))
+				},
+				Description: "The PostgreSQL object type the privileges apply to (one of: table, sequence, function, type, schema)",
+			},
+			"privileges": {
+				Type:        schema.TypeSet,
+				Required:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Set:         schema.HashString,
+				MinItems:    1,
+				Description: "The list of privileges to grant",
+			},
+			"resource_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
+			},
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
+			},
+		},
+	}
+}
+
+// defaultPrivilegesType returns the object type of default privileges, and
+// the privileges that can be granted on it.
+func defaultPrivilegesType(d resourceGetter) (defaultPrivilegesObjectType, []string) {
+	name := normalizeGrantObjectType(d.Get("object_type").(string))
+	return defaultPrivilegesObjectTypes[name], grantObjectTypes[name].privileges
+}
+
+func resourceAwsRdsdataservicePostgresDefaultPrivilegesCreate(d *schema.ResourceData, meta interface{}) error {
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+	_, valid := defaultPrivilegesType(d)
+	privileges := setToSortedStrings(grantPrivileges(valid, d.Get("privileges").(*schema.Set)))
+
+	// Defaults set outside Terraform are revoked, so that new objects get
+	// exactly the configured privileges
+	statements := []string{
+		defaultPrivilegesStatement(d, "REVOKE", []string{"ALL PRIVILEGES"}),
+		defaultPrivilegesStatement(d, "GRANT", privileges),
+	}
+
+	if err := alterDefaultPrivileges(d, target, meta, statements, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return fmt.Errorf("Error creating Postgres Default Privileges: %#v", err)
+	}
+
+	d.SetId(generateDefaultPrivilegesID(d, target.Database))
+
+	return resourceAwsRdsdataservicePostgresDefaultPrivilegesRead(d, meta)
+}
+
+func generateDefaultPrivilegesID(d *schema.ResourceData, database string) string {
+	return strings.Join([]string{
+		d.Get("role").(string), database, d.Get("owner").(string),
+		d.Get("schema").(string), normalizeGrantObjectType(d.Get("object_type").(string)),
+	}, "_")
+}
+
+func resourceAwsRdsdataservicePostgresDefaultPrivilegesRead(d *schema.ResourceData, meta interface{}) error {
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+
+	for _, role := range []string{d.Get("role").(string), d.Get("owner").(string)} {
+		exists, err := roleExists(role, target, meta)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			log.Printf("[WARN] Postgres Role %s not found, removing Default Privileges from state", role)
+			d.SetId("")
+			return nil
+		}
+	}
+
+	objectType, valid := defaultPrivilegesType(d)
+	rows, err := queryRows(meta, target, `
+SELECT array_remove(array_agg(acl.privilege_type), NULL) AS privileges
+FROM pg_default_acl
+JOIN pg_roles owner ON owner.oid = pg_default_acl.defaclrole
+LEFT JOIN pg_namespace ON pg_namespace.oid = pg_default_acl.defaclnamespace
+LEFT JOIN LATERAL aclexplode(pg_default_acl.defaclacl) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE owner.rolname = :owner
+    AND COALESCE(pg_namespace.nspname, '') = :schema
+    AND pg_default_acl.defaclobjtype = :objtype`,
+		stringParameter("role", d.Get("role").(string)),
+		stringParameter("owner", d.Get("owner").(string)),
+		stringParameter("schema", d.Get("schema").(string)),
+		stringParameter("objtype", objectType.objtype))
+
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Default Privileges: %#v", err)
+	}
+
+	// Without an entry the role has no default privileges
+	var privileges []string
+	if len(rows) == 1 {
+		if privileges, err = rows[0].getStringList("privileges"); err != nil {
+			return fmt.Errorf("Error reading Postgres Default Privileges: %s", err)
+		}
+	}
+
+	granted := stringSet(privileges)
+	if !granted.Equal(grantPrivileges(valid, d.Get("privileges").(*schema.Set))) {
+		d.Set("privileges", granted)
+	}
+
+	// Imported IDs may spell the object type in any accepted form
+	d.Set("object_type", normalizeGrantObjectType(d.Get("object_type").(string)))
+
+	return nil
+}
+
+func resourceAwsRdsdataservicePostgresDefaultPrivilegesUpdate(d *schema.ResourceData, meta interface{}) error {
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+	_, valid := defaultPrivilegesType(d)
+
+	o, n := d.GetChange("privileges")
+	old := grantPrivileges(valid, o.(*schema.Set))
+	new := grantPrivileges(valid, n.(*schema.Set))
+
+	statements := []string{}
+	if removed := setToSortedStrings(old.Difference(new)); len(removed) > 0 {
+		statements = append(statements, defaultPrivilegesStatement(d, "REVOKE", removed))
+	}
+	if added := setToSortedStrings(new.Difference(old)); len(added) > 0 {
+		statements = append(statements, defaultPrivilegesStatement(d, "GRANT", added))
+	}
+
+	if len(statements) > 0 {
+		if err := alterDefaultPrivileges(d, target, meta, statements, d.Timeout(schema.TimeoutUpdate)); err != nil {
+			return fmt.Errorf("Error updating Postgres Default Privileges: %#v", err)
+		}
+	}
+
+	return resourceAwsRdsdataservicePostgresDefaultPrivilegesRead(d, meta)
+}
+
+func resourceAwsRdsdataservicePostgresDefaultPrivilegesDelete(d *schema.ResourceData, meta interface{}) error {
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+
+	statements := []string{defaultPrivilegesStatement(d, "REVOKE", []string{"ALL PRIVILEGES"})}
+	if err := alterDefaultPrivileges(d, target, meta, statements, d.Timeout(schema.TimeoutDelete)); err != nil {
+		return fmt.Errorf("Error dropping Postgres Default Privileges: %#v", err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// alterDefaultPrivileges runs statements in a transaction.
+func alterDefaultPrivileges(d *schema.ResourceData, target dataAPITarget, meta interface{}, statements []string, timeout time.Duration) error {
+	executor := meta.(*AWSClient).executor
+
+	return withTransaction(executor, target, func(transactionID *string) error {
+		for _, sql := range statements {
+			alterOpts := target.statement(sql)
+			alterOpts.TransactionId = transactionID
+
+			log.Printf("[DEBUG] Alter Postgres Default Privileges: %#v", alterOpts)
+
+			if err := executeDDL(executor, &alterOpts, timeout, statementCompleted(executor, target, transactionID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// defaultPrivilegesStatement returns the ALTER DEFAULT PRIVILEGES statement
+// granting or revoking privileges.
+func defaultPrivilegesStatement(d resourceGetter, action string, privileges []string) string {
+	objectType, _ := defaultPrivilegesType(d)
+
+	sql := pgsql.Format("ALTER DEFAULT PRIVILEGES FOR ROLE %I", d.Get("owner").(string))
+	if schemaName := d.Get("schema").(string); schemaName != "" {
+		sql += pgsql.Format(" IN SCHEMA %I", schemaName)
+	}
+
+	preposition := "TO"
+	if action == "REVOKE" {
+		preposition = "FROM"
+	}
+
+	return fmt.Sprintf("%s %s %s ON %s %s %s",
+		sql,
+		action,
+		strings.Join(privileges, ","),
+		objectType.keyword,
+		preposition,
+		pgsql.Ident(d.Get("role").(string)),
+	)
+}
+
+// customizeDiffDefaultPrivileges checks the schema and privileges against
+// the object type.
+func customizeDiffDefaultPrivileges(diff *schema.ResourceDiff, meta interface{}) error {
+	if !diff.NewValueKnown("object_type") {
+		return nil
+	}
+	name := normalizeGrantObjectType(diff.Get("object_type").(string))
+
+	// Schemas are created in the database, not in a schema
+	if name == "schema" && diff.NewValueKnown("schema") && diff.Get("schema").(string) != "" {
+		return fmt.Errorf("schema cannot be set for default privileges on schemas")
+	}
+
+	if !diff.NewValueKnown("privileges") {
+		return nil
+	}
+	_, valid := defaultPrivilegesType(diff)
+	for _, privilege := range setToSortedStrings(grantPrivileges(valid, diff.Get("privileges").(*schema.Set))) {
+		if !stringInSlice(privilege, valid) {
+			return fmt.Errorf("privilege %s cannot be granted on a %s, expected ALL or any of %s", privilege, name, strings.Join(valid, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func testPostgresDefaultPrivilegesConfig(schemaName string, privileges ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"role":         "reader",
+		"owner":        "migrator",
+		"database":     "app",
+		"schema":       schemaName,
+		"object_type":  "table",
+		"privileges":   privileges,
+		"resource_arn": testResourceArn,
+		"secret_arn":   testSecretArn,
+	}
+}
+
+// testDefaultPrivilegesExecutor returns an executor for which both roles
+// exist and the role holds privileges by default.
+func testDefaultPrivilegesExecutor(privileges string) *fakeExecutor {
+	return (&fakeExecutor{}).
+		on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+		onRows("FROM pg_default_acl", []string{"privileges"}, testRecord(testStringField(privileges)))
+}
+
+func TestResourceAwsRdsdataservicePostgresDefaultPrivilegesCreate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schema   string
+		expected []string
+	}{
+		{
+			name:   "in schema",
+			schema: "public",
+			expected: []string{
+				"BEGIN",
+				`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" IN SCHEMA "public" REVOKE ALL PRIVILEGES ON TABLES FROM "reader"`,
+				`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" IN SCHEMA "public" GRANT SELECT ON TABLES TO "reader"`,
+				"COMMIT",
+			},
+		},
+		{
+			name: "every schema",
+			expected: []string{
+				"BEGIN",
+				`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" REVOKE ALL PRIVILEGES ON TABLES FROM "reader"`,
+				`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" GRANT SELECT ON TABLES TO "reader"`,
+				"COMMIT",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := testDefaultPrivilegesExecutor("{SELECT}")
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDefaultPrivileges().Schema, testPostgresDefaultPrivilegesConfig(testCase.schema, "SELECT"))
+
+			if err := resourceAwsRdsdataservicePostgresDefaultPrivilegesCreate(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			// The statements after COMMIT read the defaults back
+			executor.statements = executor.statements[:len(testCase.expected)]
+			executor.expectStatements(t, testCase.expected...)
+			for _, input := range executor.inputs {
+				if v := aws.StringValue(input.Database); v != "app" {
+					t.Fatalf("statement %q ran in database %q", aws.StringValue(input.Sql), v)
+				}
+			}
+			if d.Id() != "reader_app_migrator_"+testCase.schema+"_table" {
+				t.Fatalf("unexpected ID: %s", d.Id())
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDefaultPrivilegesRead(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured []interface{}
+		privileges string
+		expected   []string
+	}{
+		{"in sync", []interface{}{"select"}, "{SELECT}", []string{"select"}},
+		{"all", []interface{}{"ALL"}, "{SELECT,INSERT,UPDATE,DELETE,TRUNCATE,REFERENCES,TRIGGER}", []string{"ALL"}},
+		{"changed", []interface{}{"SELECT"}, "{SELECT,INSERT}", []string{"INSERT", "SELECT"}},
+		{"revoked", []interface{}{"SELECT"}, "", []string{}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).on("SELECT 1 FROM pg_roles", testRecord(testLongField(1)))
+			if testCase.privileges != "" {
+				executor.onRows("FROM pg_default_acl", []string{"privileges"}, testRecord(testStringField(testCase.privileges)))
+			}
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDefaultPrivileges().Schema, testPostgresDefaultPrivilegesConfig("public", testCase.configured...))
+			d.SetId("reader_app_migrator_public_table")
+
+			if err := resourceAwsRdsdataservicePostgresDefaultPrivilegesRead(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectParameters(t, 2, map[string]string{"role": "reader", "owner": "migrator", "schema": "public", "objtype": "r"})
+			if got := setToSortedStrings(d.Get("privileges").(*schema.Set)); strings.Join(got, ",") != strings.Join(testCase.expected, ",") {
+				t.Fatalf("got privileges %v, expected %v", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDefaultPrivilegesReadRoleNotFound(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDefaultPrivileges().Schema, testPostgresDefaultPrivilegesConfig("public", "SELECT"))
+	d.SetId("reader_app_migrator_public_table")
+
+	if err := resourceAwsRdsdataservicePostgresDefaultPrivilegesRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "" {
+		t.Fatalf("expected resource to be removed from state, got ID %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDefaultPrivilegesUpdate(t *testing.T) {
+	executor := testDefaultPrivilegesExecutor("{SELECT,UPDATE}")
+	client := &AWSClient{executor: executor}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresDefaultPrivileges(), "reader_app_migrator_public_table",
+		testPostgresDefaultPrivilegesConfig("public", "SELECT", "INSERT"),
+		testPostgresDefaultPrivilegesConfig("public", "SELECT", "UPDATE"),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresDefaultPrivilegesUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.statements = executor.statements[:4]
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" IN SCHEMA "public" REVOKE INSERT ON TABLES FROM "reader"`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" IN SCHEMA "public" GRANT UPDATE ON TABLES TO "reader"`,
+		"COMMIT",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresDefaultPrivilegesDelete(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresDefaultPrivilegesConfig("", "EXECUTE")
+	config["object_type"] = "functions"
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDefaultPrivileges().Schema, config)
+	d.SetId("reader_app_migrator__function")
+
+	if err := resourceAwsRdsdataservicePostgresDefaultPrivilegesDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "migrator" REVOKE ALL PRIVILEGES ON FUNCTIONS FROM "reader"`,
+		"COMMIT",
+	)
+	if d.Id() != "" {
+		t.Fatalf("expected no ID, got %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresDefaultPrivilegesValidation(t *testing.T) {
+	testCases := []struct {
+		name       string
+		objectType string
+		schema     string
+		privileges []interface{}
+		expectErr  string
+	}{
+		{"table", "table", "public", []interface{}{"SELECT"}, ""},
+		{"schemas", "schema", "", []interface{}{"USAGE"}, ""},
+		{"schemas in a schema", "schema", "public", []interface{}{"USAGE"}, "schema cannot be set"},
+		{"table privilege on types", "type", "public", []interface{}{"SELECT"}, "privilege SELECT cannot be granted on a type"},
+		{"procedures", "procedure", "public", []interface{}{"EXECUTE"}, "expected object_type to be one of"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := testPostgresDefaultPrivilegesConfig(testCase.schema, testCase.privileges...)
+			config["object_type"] = testCase.objectType
+
+			_, err := resourceAwsRdsdataservicePostgresDefaultPrivileges().Diff(nil, terraform.NewResourceConfigRaw(config), &AWSClient{})
+			if err == nil {
+				if _, errs := resourceAwsRdsdataservicePostgresDefaultPrivileges().Validate(terraform.NewResourceConfigRaw(config)); len(errs) > 0 {
+					err = errs[0]
+				}
+			}
+
+			if testCase.expectErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func resourceAwsRdsdataservicePostgresExtension() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsRdsdataservicePostgresExtensionCreate,
+		Read:   resourceAwsRdsdataservicePostgresExtensionRead,
+		Update: resourceAwsRdsdataservicePostgresExtensionUpdate,
+		Delete: resourceAwsRdsdataservicePostgresExtensionDelete,
+		Importer: dataAPIImport{
+			resourceType: "rdsdataservice_postgres_extension",
+			database:     true,
+			names:        []string{"name"},
+			id: func(d *schema.ResourceData, target dataAPITarget) string {
+				return generateExtensionID(d, target.Database)
+			},
+		}.importer(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffDataAPITarget(true),
+			customizeDiffExtensionAvailable,
+		),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The name of the extension",
+			},
+			"database": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The database to create the extension in. Defaults to the provider database.",
+			},
+			"schema": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The schema the extension's objects are created in. Defaults to the first schema of the search_path",
+			},
+			"version": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The version of the extension. Defaults to the default version of the cluster",
+			},
+			"drop_cascade": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to also drop the objects that depend on the extension when it is dropped",
+			},
+			"resource_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
+			},
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
+			},
+		},
+	}
+}
+
+func resourceAwsRdsdataservicePostgresExtensionCreate(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+	name := d.Get("name").(string)
+	version := d.Get("version").(string)
+
+	sql := pgsql.Format("CREATE EXTENSION %I", name)
+	if v := d.Get("schema").(string); v != "" {
+		sql += pgsql.Format(" SCHEMA %I", v)
+	}
+	if version != "" {
+		sql += pgsql.Format(" VERSION %L", version)
+	}
+
+	createOpts := target.statement(sql)
+
+	log.Printf("[DEBUG] Create Postgres Extension: %#v", createOpts)
+
+	err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutCreate), func() (bool, error) {
+		row, err := readExtension(name, target, meta)
+		return row != nil && (version == "" || row.getString("version") == version), err
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error creating Postgres Extension %s: %#v", name, err)
+	}
+
+	d.SetId(generateExtensionID(d, target.Database))
+
+	return resourceAwsRdsdataservicePostgresExtensionRead(d, meta)
+}
+
+func generateExtensionID(d *schema.ResourceData, database string) string {
+	return databaseObjectID(database, d.Get("name").(string))
+}
+
+// readExtension returns the version and schema of the extension name, or nil
+// if it is not installed.
+func readExtension(name string, target dataAPITarget, meta interface{}) (resultRow, error) {
+	rows, err := queryRows(meta, target, `
+SELECT pg_extension.extversion AS version, pg_namespace.nspname AS schema
+FROM pg_extension
+JOIN pg_namespace ON pg_namespace.oid = pg_extension.extnamespace
+WHERE pg_extension.extname = :name`,
+		stringParameter("name", name))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error checking extension exists: %#v", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return rows[0], nil
+}
+
+func resourceAwsRdsdataservicePostgresExtensionRead(d *schema.ResourceData, meta interface{}) error {
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+
+	row, err := readExtension(d.Get("name").(string), target, meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Extension: %#v", err)
+	}
+
+	if row == nil {
+		log.Printf("[WARN] Postgres Extension %s not found, removing from state", d.Get("name").(string))
+		d.SetId("")
+		return nil
+	}
+
+	log.Printf("[DEBUG] Read Postgres Extension details: %#v", row)
+	d.Set("version", row.getString("version"))
+	d.Set("schema", row.getString("schema"))
+
+	return nil
+}
+
+func resourceAwsRdsdataservicePostgresExtensionUpdate(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+	name := d.Get("name").(string)
+
+	if d.HasChange("version") {
+		version := d.Get("version").(string)
+
+		sql := pgsql.Format("ALTER EXTENSION %I UPDATE", name)
+		if version != "" {
+			sql += pgsql.Format(" TO %L", version)
+		}
+
+		updateOpts := target.statement(sql)
+
+		log.Printf("[DEBUG] Update Postgres Extension version: %#v", updateOpts)
+
+		err := executeDDL(executor, &updateOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			row, err := readExtension(name, target, meta)
+			return row != nil && (version == "" || row.getString("version") == version), err
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error updating Postgres Extension version: %#v", err)
+		}
+	}
+
+	if d.HasChange("schema") {
+		schemaName := d.Get("schema").(string)
+		if schemaName == "" {
+			return fmt.Errorf("Error setting Extension schema to an empty string")
+		}
+
+		updateOpts := target.statement(pgsql.Format("ALTER EXTENSION %I SET SCHEMA %I", name, schemaName))
+
+		log.Printf("[DEBUG] Update Postgres Extension schema: %#v", updateOpts)
+
+		err := executeDDL(executor, &updateOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			row, err := readExtension(name, target, meta)
+			return row != nil && row.getString("schema") == schemaName, err
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error updating Postgres Extension schema: %#v", err)
+		}
+	}
+
+	return resourceAwsRdsdataservicePostgresExtensionRead(d, meta)
+}
+
+func resourceAwsRdsdataservicePostgresExtensionDelete(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+	name := d.Get("name").(string)
+
+	sql := pgsql.Format("DROP EXTENSION %I", name)
+	if d.Get("drop_cascade").(bool) {
+		sql += " CASCADE"
+	}
+
+	deleteOpts := target.statement(sql)
+
+	log.Printf("[DEBUG] Drop Postgres Extension: %#v", deleteOpts)
+
+	err := executeDDL(executor, &deleteOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		row, err := readExtension(name, target, meta)
+		return row == nil, err
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error dropping Postgres Extension %s: %#v", name, err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// customizeDiffExtensionAvailable fails the plan of an extension, or of a
+// version of it, that the cluster does not offer, rather than leaving
+// CREATE EXTENSION to fail at apply time. Aurora only ships a subset of the
+// contrib modules, listed in pg_available_extensions.
+func customizeDiffExtensionAvailable(diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Id() != "" && !diff.HasChange("name") && !diff.HasChange("version") {
+		return nil
+	}
+	for _, key := range []string{"name", "resource_arn", "secret_arn"} {
+		if !diff.NewValueKnown(key) {
+			return nil
+		}
+	}
+
+	// The available extensions are the same in every database, so they are
+	// looked up in the provider database: the extension's own database may
+	// be created in the same apply
+	name := diff.Get("name").(string)
+	target := resourceTarget(diff, meta)
+
+	rows, err := queryRows(meta, target, `
+SELECT pg_available_extension_versions.version
+FROM pg_available_extensions
+JOIN pg_available_extension_versions ON pg_available_extension_versions.name = pg_available_extensions.name
+WHERE pg_available_extensions.name = :name
+ORDER BY pg_available_extension_versions.version`,
+		stringParameter("name", name))
+
+	if err != nil {
+		return fmt.Errorf("Error checking Postgres Extension %s is available: %#v", name, err)
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("Postgres Extension %s is not available on the cluster, see pg_available_extensions for the extensions it offers", name)
+	}
+
+	// An unset version is computed, and unknown until the extension exists
+	version := diff.Get("version").(string)
+	if version == "" || !diff.NewValueKnown("version") {
+		return nil
+	}
+
+	versions := make([]string, len(rows))
+	for i, row := range rows {
+		versions[i] = row.getString("version")
+	}
+	if !stringInSlice(version, versions) {
+		return fmt.Errorf("version %s of Postgres Extension %s is not available on the cluster, expected one of [%s]", version, name, strings.Join(versions, " "))
+	}
+
+	return nil
+}
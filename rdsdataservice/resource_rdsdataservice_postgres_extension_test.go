@@ -0,0 +1,217 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func testPostgresExtensionConfig(schemaName, version string) map[string]interface{} {
+	config := map[string]interface{}{
+		"name":         "pgcrypto",
+		"database":     "app",
+		"resource_arn": testResourceArn,
+		"secret_arn":   testSecretArn,
+	}
+	if schemaName != "" {
+		config["schema"] = schemaName
+	}
+	if version != "" {
+		config["version"] = version
+	}
+	return config
+}
+
+// testExtensionExecutor returns an executor for which pgcrypto is installed
+// with version in schemaName.
+func testExtensionExecutor(schemaName, version string) *fakeExecutor {
+	return (&fakeExecutor{}).
+		onRows("FROM pg_extension", []string{"version", "schema"}, testRecord(testStringField(version), testStringField(schemaName)))
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionCreate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schema   string
+		version  string
+		expected string
+	}{
+		{"defaults", "", "", `CREATE EXTENSION "pgcrypto"`},
+		{"schema and version", "crypto", "1.3", `CREATE EXTENSION "pgcrypto" SCHEMA "crypto" VERSION '1.3'`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := testExtensionExecutor("public", "1.3")
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresExtension().Schema, testPostgresExtensionConfig(testCase.schema, testCase.version))
+
+			if err := resourceAwsRdsdataservicePostgresExtensionCreate(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if executor.statements[0] != testCase.expected {
+				t.Fatalf("got statement %q, expected %q", executor.statements[0], testCase.expected)
+			}
+			if v := *executor.inputs[0].Database; v != "app" {
+				t.Fatalf("statement ran in database %q", v)
+			}
+			if d.Id() != "app.pgcrypto" {
+				t.Fatalf("unexpected ID: %s", d.Id())
+			}
+			if v := d.Get("version").(string); v != "1.3" {
+				t.Fatalf("unexpected version: %s", v)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionRead(t *testing.T) {
+	executor := testExtensionExecutor("crypto", "1.2")
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresExtension().Schema, testPostgresExtensionConfig("public", "1.3"))
+	d.SetId("app.pgcrypto")
+
+	if err := resourceAwsRdsdataservicePostgresExtensionRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectParameters(t, 0, map[string]string{"name": "pgcrypto"})
+	if v := d.Get("schema").(string); v != "crypto" {
+		t.Fatalf("unexpected schema: %s", v)
+	}
+	if v := d.Get("version").(string); v != "1.2" {
+		t.Fatalf("unexpected version: %s", v)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionReadNotFound(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresExtension().Schema, testPostgresExtensionConfig("", ""))
+	d.SetId("app.pgcrypto")
+
+	if err := resourceAwsRdsdataservicePostgresExtensionRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "" {
+		t.Fatalf("expected resource to be removed from state, got ID %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionUpdate(t *testing.T) {
+	executor := testExtensionExecutor("crypto", "1.3").
+		onRows("FROM pg_available_extensions", []string{"version"}, testRecord(testStringField("1.3")))
+	client := &AWSClient{executor: executor}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresExtension(), "app.pgcrypto",
+		testPostgresExtensionConfig("public", "1.2"),
+		testPostgresExtensionConfig("crypto", "1.3"),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresExtensionUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var statements []string
+	for _, sql := range executor.statements {
+		if strings.HasPrefix(sql, "ALTER") {
+			statements = append(statements, sql)
+		}
+	}
+	executor.statements = statements
+	executor.expectStatements(t,
+		`ALTER EXTENSION "pgcrypto" UPDATE TO '1.3'`,
+		`ALTER EXTENSION "pgcrypto" SET SCHEMA "crypto"`,
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionDelete(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cascade  bool
+		expected string
+	}{
+		{"restrict", false, `DROP EXTENSION "pgcrypto"`},
+		{"cascade", true, `DROP EXTENSION "pgcrypto" CASCADE`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			config := testPostgresExtensionConfig("", "")
+			config["drop_cascade"] = testCase.cascade
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresExtension().Schema, config)
+			d.SetId("app.pgcrypto")
+
+			if err := resourceAwsRdsdataservicePostgresExtensionDelete(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.statements = executor.statements[:1]
+			executor.expectStatements(t, testCase.expected)
+			if d.Id() != "" {
+				t.Fatalf("expected no ID, got %s", d.Id())
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionAvailable(t *testing.T) {
+	testCases := []struct {
+		name      string
+		version   string
+		versions  []string
+		expectErr string
+	}{
+		{"default version", "", []string{"1.2", "1.3"}, ""},
+		{"available version", "1.3", []string{"1.2", "1.3"}, ""},
+		{"not available", "", nil, "Postgres Extension pgcrypto is not available on the cluster"},
+		{"version not available", "2.0", []string{"1.2", "1.3"}, "expected one of [1.2 1.3]"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var records [][]*rdsdataservice.Field
+			for _, version := range testCase.versions {
+				records = append(records, testRecord(testStringField(version)))
+			}
+			executor := (&fakeExecutor{}).onRows("FROM pg_available_extensions", []string{"version"}, records...)
+
+			_, err := resourceAwsRdsdataservicePostgresExtension().Diff(nil, terraform.NewResourceConfigRaw(testPostgresExtensionConfig("", testCase.version)), &AWSClient{executor: executor})
+
+			executor.expectParameters(t, 0, map[string]string{"name": "pgcrypto"})
+			if testCase.expectErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresExtensionAvailableInNewDatabase(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_available_extensions", []string{"version"}, testRecord(testStringField("1.3")))
+	config := testPostgresExtensionConfig("", "")
+	config["database"] = "tenant"
+
+	if _, err := resourceAwsRdsdataservicePostgresExtension().Diff(nil, terraform.NewResourceConfigRaw(config), testProviderDefaultsClient(executor)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(executor.inputs) == 0 {
+		t.Fatal("expected the availability to be checked")
+	}
+	for _, input := range executor.inputs {
+		if v := aws.StringValue(input.Database); v != "postgres" {
+			t.Fatalf("availability was checked in database %q", v)
+		}
+	}
+}
@@ -3,60 +3,318 @@ package rdsdataservice
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rdsdataservice"
-	"github.com/lib/pq"
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
 
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
-var objectTypes = map[string]string{
-	"table":    "r",
-	"sequence": "S",
+// grantScope is how the objects of a grantObjectType are named in GRANT
+// and REVOKE statements.
+type grantScope int
+
+const (
+	// grantScopeAllInSchema grants ON ALL <keyword> IN SCHEMA.
+	grantScopeAllInSchema grantScope = iota
+	// grantScopeDatabase grants ON DATABASE, the grant's database.
+	grantScopeDatabase
+	// grantScopeSchema grants ON SCHEMA.
+	grantScopeSchema
+	// grantScopeEachInSchema names each object of the schema, for types
+	// that have no ALL ... IN SCHEMA form.
+	grantScopeEachInSchema
+)
+
+// grantObjectType describes a kind of object privileges are granted on.
+type grantObjectType struct {
+	scope grantScope
+	// keyword names the objects in the ON clause.
+	keyword string
+	// object names listed objects of the type in the ON clause. Types
+	// without it cannot be granted on listed objects.
+	object string
+	// kind is the pg_class.relkind or pg_proc.prokind of the objects, bound
+	// as :kind in acl.
+	kind string
+	// privileges lists the privileges that can be granted, which are also
+	// what ALL stands for.
+	privileges []string
+	// columnPrivileges lists the privileges that can be granted on columns.
+	// Types without them cannot be granted on columns.
+	columnPrivileges []string
+	// acl returns the name of each object and the privileges :role holds
+	// on it, and of those the ones it may grant. The objects are in
+	// :schema, or :database for databases.
+	acl string
+	// acl10 replaces acl before PostgreSQL 11, for routines.
+	acl10 string
+	// overloaded is set for routines, which are named with their argument
+	// types in the ON clause. Their acl returns one row per overload, with
+	// its arguments.
+	overloaded bool
+}
+
+// grantObjectTypes are the accepted values of object_type. Objects without
+// an ACL of their own are read with the default privileges of their owner.
+var grantObjectTypes = map[string]grantObjectType{
+	"table": {
+		keyword:          "TABLES",
+		object:           "TABLE",
+		kind:             "r",
+		privileges:       []string{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+		columnPrivileges: []string{"SELECT", "INSERT", "UPDATE", "REFERENCES"},
+		acl:              relationACL,
+	},
+	"sequence": {
+		keyword:    "SEQUENCES",
+		object:     "SEQUENCE",
+		kind:       "S",
+		privileges: []string{"USAGE", "SELECT", "UPDATE"},
+		acl:        relationACL,
+	},
+	"function": {
+		keyword:    "FUNCTIONS",
+		object:     "FUNCTION",
+		kind:       "f",
+		privileges: []string{"EXECUTE"},
+		acl:        routineACL,
+		acl10:      routineACL10,
+		overloaded: true,
+	},
+	"procedure": {
+		keyword:    "PROCEDURES",
+		object:     "PROCEDURE",
+		kind:       "p",
+		privileges: []string{"EXECUTE"},
+		acl:        routineACL,
+		acl10:      routineACL10,
+		overloaded: true,
+	},
+	"type": {
+		scope:      grantScopeEachInSchema,
+		object:     "TYPE",
+		privileges: []string{"USAGE"},
+		acl: `
+SELECT pg_type.typname AS name,
+    array_remove(array_agg(acl.privilege_type), NULL) AS privileges,
+    array_remove(array_agg(CASE WHEN acl.is_grantable THEN acl.privilege_type END), NULL) AS grantable
+FROM pg_type
+JOIN pg_namespace ON pg_namespace.oid = pg_type.typnamespace
+LEFT JOIN pg_class ON pg_class.oid = pg_type.typrelid
+LEFT JOIN LATERAL aclexplode(COALESCE(pg_type.typacl, acldefault('T', pg_type.typowner))) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE pg_namespace.nspname = :schema
+    AND pg_type.typcategory <> 'A'
+    AND pg_type.typtype <> 'm'
+    AND (pg_type.typrelid = 0 OR pg_class.relkind = 'c')
+GROUP BY pg_type.typname
+ORDER BY pg_type.typname`,
+	},
+	"database": {
+		scope:      grantScopeDatabase,
+		keyword:    "DATABASE",
+		privileges: []string{"CREATE", "CONNECT", "TEMPORARY"},
+		acl: `
+SELECT pg_database.datname AS name,
+    array_remove(array_agg(acl.privilege_type), NULL) AS privileges,
+    array_remove(array_agg(CASE WHEN acl.is_grantable THEN acl.privilege_type END), NULL) AS grantable
+FROM pg_database
+LEFT JOIN LATERAL aclexplode(COALESCE(pg_database.datacl, acldefault('d', pg_database.datdba))) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE pg_database.datname = :database
+GROUP BY pg_database.datname`,
+	},
+	"schema": {
+		scope:      grantScopeSchema,
+		keyword:    "SCHEMA",
+		privileges: []string{"USAGE", "CREATE"},
+		acl: `
+SELECT pg_namespace.nspname AS name,
+    array_remove(array_agg(acl.privilege_type), NULL) AS privileges,
+    array_remove(array_agg(CASE WHEN acl.is_grantable THEN acl.privilege_type END), NULL) AS grantable
+FROM pg_namespace
+LEFT JOIN LATERAL aclexplode(COALESCE(pg_namespace.nspacl, acldefault('n', pg_namespace.nspowner))) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE pg_namespace.nspname = :schema
+GROUP BY pg_namespace.nspname`,
+	},
+}
+
+const relationACL = `
+SELECT pg_class.relname AS name,
+    array_remove(array_agg(acl.privilege_type), NULL) AS privileges,
+    array_remove(array_agg(CASE WHEN acl.is_grantable THEN acl.privilege_type END), NULL) AS grantable
+FROM pg_class
+JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+LEFT JOIN LATERAL aclexplode(COALESCE(pg_class.relacl, acldefault(CASE WHEN pg_class.relkind = 'S' THEN 's' ELSE 'r' END::"char", pg_class.relowner))) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE pg_namespace.nspname = :schema AND pg_class.relkind = :kind
+GROUP BY pg_class.relname
+ORDER BY pg_class.relname`
+
+// routineACLFormat lists the routines of :kind, 'f' for functions or 'p'
+// for procedures, given the expression of the kind of a pg_proc row.
+// Overloaded routines are listed once per signature, with the argument
+// types that name it.
+const routineACLFormat = `
+SELECT pg_proc.proname AS name, pg_get_function_identity_arguments(pg_proc.oid) AS arguments,
+    array_remove(array_agg(acl.privilege_type), NULL) AS privileges,
+    array_remove(array_agg(CASE WHEN acl.is_grantable THEN acl.privilege_type END), NULL) AS grantable
+FROM pg_proc
+JOIN pg_namespace ON pg_namespace.oid = pg_proc.pronamespace
+LEFT JOIN LATERAL aclexplode(COALESCE(pg_proc.proacl, acldefault('f', pg_proc.proowner))) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE pg_namespace.nspname = :schema AND %s = :kind
+GROUP BY pg_proc.oid, pg_proc.proname
+ORDER BY pg_proc.proname, arguments`
+
+var (
+	// routineACL counts aggregates ('a') and window functions ('w') as
+	// functions, as GRANT ... ON ALL FUNCTIONS does.
+	routineACL = fmt.Sprintf(routineACLFormat, "CASE pg_proc.prokind WHEN 'p' THEN 'p' ELSE 'f' END")
+	// routineACL10 is routineACL before PostgreSQL 11, which has no
+	// pg_proc.prokind and no procedures: plain, aggregate (proisagg) and
+	// window (proiswindow) functions are all functions.
+	routineACL10 = fmt.Sprintf(routineACLFormat, "'f'")
+)
+
+// columnACL lists the columns of the tables in :schema. Columns have no
+// privileges unless granted on the column, so there is no default ACL.
+const columnACL = `
+SELECT pg_class.relname AS name, pg_attribute.attname AS column_name,
+    array_remove(array_agg(acl.privilege_type), NULL) AS privileges,
+    array_remove(array_agg(CASE WHEN acl.is_grantable THEN acl.privilege_type END), NULL) AS grantable
+FROM pg_attribute
+JOIN pg_class ON pg_class.oid = pg_attribute.attrelid
+JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
+LEFT JOIN LATERAL aclexplode(pg_attribute.attacl) acl
+    ON acl.grantee = (SELECT oid FROM pg_roles WHERE rolname = :role)
+WHERE pg_namespace.nspname = :schema AND pg_class.relkind = :kind
+    AND pg_attribute.attnum > 0 AND NOT pg_attribute.attisdropped
+GROUP BY pg_class.relname, pg_attribute.attname
+ORDER BY pg_class.relname, pg_attribute.attname`
+
+// normalizeGrantObjectType returns the grantObjectTypes key of an
+// object_type, which may also be given in upper case or in the plural.
+func normalizeGrantObjectType(v string) string {
+	name := strings.ToLower(v)
+	if _, ok := grantObjectTypes[name]; ok {
+		return name
+	}
+	if singular := strings.TrimSuffix(name, "s"); singular != name {
+		if _, ok := grantObjectTypes[singular]; ok {
+			return singular
+		}
+	}
+	return v
+}
+
+func validateGrantObjectType(v interface{}, k string) (ws []string, errors []error) {
+	if _, ok := grantObjectTypes[normalizeGrantObjectType(v.(string))]; !ok {
+		names := make([]string, 0, len(grantObjectTypes))
+		for name := range grantObjectTypes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errors = append(errors, fmt.Errorf("expected %s to be one of %v, got %s", k, names, v))
+	}
+	return
+}
+
+// objectType returns the object type of a grant. During apply the
+// configured object_type is seen before StateFunc normalizes it.
+func objectType(d resourceGetter) grantObjectType {
+	return grantObjectTypes[normalizeGrantObjectType(d.Get("object_type").(string))]
+}
+
+// validPrivileges returns the privileges that can be granted with the
+// grant's object type and columns.
+func validPrivileges(d resourceGetter) []string {
+	if d.Get("columns").(*schema.Set).Len() > 0 {
+		return objectType(d).columnPrivileges
+	}
+	return objectType(d).privileges
 }
 
 func resourceAwsRdsdataservicePostgresGrant() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceAwsRdsdataservicePostgresGrantCreate,
 		Read:   resourceAwsRdsdataservicePostgresGrantRead,
-		// As create revokes and grants we can use it to update too
-		Update: resourceAwsRdsdataservicePostgresGrantCreate,
+		Update: resourceAwsRdsdataservicePostgresGrantUpdate,
 		Delete: resourceAwsRdsdataservicePostgresGrantDelete,
-		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+		Importer: dataAPIImport{
+			resourceType: "rdsdataservice_postgres_grant",
+			database:     true,
+			names:        []string{"role", "schema", "object_type"},
+			// Database grants have no schema
+			optional: []string{"schema"},
+			id: func(d *schema.ResourceData, target dataAPITarget) string {
+				return generateGrantID(d, target.Database)
+			},
+		}.importer(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffDataAPITarget(true),
+			customizeDiffGrantPrivileges,
+		),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
 			"role": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The name of the role to grant privileges on",
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The name of the role to grant privileges on",
 			},
 			"database": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The database to grant privileges on for this role",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The database to grant privileges on for this role. Defaults to the provider database",
 			},
 			"schema": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The database schema to grant privileges on for this role",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The database schema to grant privileges on for this role. Required unless object_type is database",
 			},
 			"object_type": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
-				/*ValidateFunc: validation.StringInSlice([]string{
-					"table",
-					"sequence",
-				}, false),
-				*/
-				Description: "The PostgreSQL object type to grant the privileges on (one of: table, sequence)",
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateGrantObjectType,
+				StateFunc: func(v interface{}) string {
+					return normalizeGrantObjectType(v.(string))
+				},
+				Description: "The PostgreSQL object type to grant the privileges on (one of: database, schema, table, sequence, function, procedure, type)",
+			},
+			"objects": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateNoNUL},
+				Set:         schema.HashString,
+				Description: "The objects in the schema to grant privileges on. Defaults to every object of the type",
+			},
+			"columns": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString, ValidateFunc: validateNoNUL},
+				Set:         schema.HashString,
+				Description: "The columns of the tables in objects to grant privileges on",
 			},
 			"privileges": &schema.Schema{
 				Type:        schema.TypeSet,
@@ -66,113 +324,343 @@ func resourceAwsRdsdataservicePostgresGrant() *schema.Resource {
 				MinItems:    1,
 				Description: "The list of privileges to grant",
 			},
-			"secret_arn": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The PostgreSQL database name to connect to",
+			"with_grant_option": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether the role may grant the privileges to others",
 			},
 			"resource_arn": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Default:     "postgres",
-				Description: "The PostgreSQL database name to connect to",
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
+			},
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
 			},
 		},
 	}
 }
 
 func resourceAwsRdsdataservicePostgresGrantCreate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
-	// TODO: Run this on transaction
-	sql := fmt.Sprintf(
-		"REVOKE ALL PRIVILEGES ON ALL %sS IN SCHEMA %s FROM %s",
-		strings.ToUpper(d.Get("object_type").(string)),
-		pq.QuoteIdentifier(d.Get("schema").(string)),
-		pq.QuoteIdentifier(d.Get("role").(string)),
-	)
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+	timeout := d.Timeout(schema.TimeoutCreate)
+
+	privileges := setToSortedStrings(grantPrivileges(validPrivileges(d), d.Get("privileges").(*schema.Set)))
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	on, err := grantOn(d, target, meta)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[DEBUG] Create Postgres Grant: step 1: revoke: %#v", createOpts)
+	// Privileges granted outside Terraform are revoked, so that the grant
+	// starts out with exactly the configured privileges
+	statements := []string{}
+	if on != "" {
+		statements = append(statements,
+			grantStatement(d, "REVOKE", []string{"ALL PRIVILEGES"}, on),
+			grantStatement(d, "GRANT", privileges, on)+grantOption(d.Get("with_grant_option").(bool)),
+		)
+	}
+
+	err = withTransaction(executor, target, func(transactionID *string) error {
+		for _, sql := range statements {
+			createOpts := target.statement(sql)
+			createOpts.TransactionId = transactionID
+
+			log.Printf("[DEBUG] Create Postgres Grant: %#v", createOpts)
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+			if err := executeDDL(executor, &createOpts, timeout, statementCompleted(executor, target, transactionID)); err != nil {
+				return fmt.Errorf("Error granting priviliges: %s to %s: %#v", strings.Join(privileges, ","), d.Get("role").(string), err)
+			}
+		}
+		return nil
+	})
 
 	if err != nil {
-		return fmt.Errorf("Error revoking Postgres grant: %#v", err)
+		return err
 	}
 
-	// Grant roles
-	privileges := []string{}
-	for _, priv := range d.Get("privileges").(*schema.Set).List() {
-		privileges = append(privileges, priv.(string))
+	d.SetId(generateGrantID(d, target.Database))
+	log.Printf("[INFO] Postgres Role ID: %s", d.Id())
+
+	return nil
+}
+
+func resourceAwsRdsdataservicePostgresGrantUpdate(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
+
+	// Only the difference is granted and revoked, so the privileges kept
+	// stay in effect throughout
+	o, n := d.GetChange("privileges")
+	old := grantPrivileges(validPrivileges(d), o.(*schema.Set))
+	new := grantPrivileges(validPrivileges(d), n.(*schema.Set))
+	removed := setToSortedStrings(old.Difference(new))
+	added := setToSortedStrings(new.Difference(old))
+	kept := setToSortedStrings(old.Intersection(new))
+
+	o, n = d.GetChange("with_grant_option")
+	oldGrantOption, newGrantOption := o.(bool), n.(bool)
+
+	if len(removed) == 0 && len(added) == 0 && oldGrantOption == newGrantOption {
+		return nil
 	}
 
-	sql = fmt.Sprintf(
-		"GRANT %s ON ALL %s IN SCHEMA %s TO %s",
-		strings.Join(privileges, ","),
-		strings.ToUpper(d.Get("object_type").(string)),
-		pq.QuoteIdentifier(d.Get("schema").(string)),
-		pq.QuoteIdentifier(d.Get("role").(string)),
-	)
+	on, err := grantOn(d, target, meta)
+	if err != nil || on == "" {
+		return err
+	}
 
-	createOpts = rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+	statements := []string{}
+	if len(removed) > 0 {
+		statements = append(statements, grantStatement(d, "REVOKE", removed, on))
+	}
+	switch {
+	case newGrantOption && !oldGrantOption:
+		// Granting the kept privileges again adds the grant option to them
+		statements = append(statements, grantStatement(d, "GRANT", setToSortedStrings(new), on)+grantOption(true))
+	case oldGrantOption && !newGrantOption && len(kept) > 0:
+		statements = append(statements, strings.Replace(grantStatement(d, "REVOKE", kept, on), "REVOKE", "REVOKE GRANT OPTION FOR", 1))
+		fallthrough
+	default:
+		if len(added) > 0 {
+			statements = append(statements, grantStatement(d, "GRANT", added, on)+grantOption(newGrantOption))
+		}
 	}
-	log.Printf("[DEBUG] Create Postgres Grant: step 2: grant: %#v", createOpts)
 
-	_, err = rdsdataserviceconn.ExecuteStatement(&createOpts)
+	return withTransaction(executor, target, func(transactionID *string) error {
+		for _, sql := range statements {
+			updateOpts := target.statement(sql)
+			updateOpts.TransactionId = transactionID
 
-	if err != nil {
-		return fmt.Errorf("Error granting priviliges: %s to %s: %#v", strings.Join(privileges, ","), d.Get("name").(string), err)
+			log.Printf("[DEBUG] Update Postgres Grant: %#v", updateOpts)
+
+			if err := executeDDL(executor, &updateOpts, d.Timeout(schema.TimeoutUpdate), statementCompleted(executor, target, transactionID)); err != nil {
+				return fmt.Errorf("Error updating Postgres Grant: %#v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// grantOn returns the ON clause naming the objects of the grant, or "" if
+// there are none to name.
+func grantOn(d resourceGetter, target dataAPITarget, meta interface{}) (string, error) {
+	objectType := objectType(d)
+	schemaName := d.Get("schema").(string)
+
+	names := setToSortedStrings(d.Get("objects").(*schema.Set))
+	switch {
+	case objectType.scope == grantScopeDatabase:
+		return "DATABASE " + pgsql.Ident(target.Database), nil
+	case objectType.scope == grantScopeSchema:
+		return "SCHEMA " + pgsql.Ident(schemaName), nil
+	case len(names) == 0 && objectType.scope == grantScopeEachInSchema:
+		rows, err := grantACL(d, objectType.acl, target, meta)
+		if err != nil {
+			return "", fmt.Errorf("Error listing Postgres %s objects: %#v", d.Get("object_type").(string), err)
+		}
+		if len(rows) == 0 {
+			log.Printf("[DEBUG] No %s in schema %s to grant privileges on", d.Get("object_type").(string), schemaName)
+			return "", nil
+		}
+		for _, row := range rows {
+			names = append(names, row.getString("name"))
+		}
+	case len(names) == 0:
+		return fmt.Sprintf("ALL %s IN SCHEMA %s", objectType.keyword, pgsql.Ident(schemaName)), nil
 	}
 
-	d.SetId(generateGrantID(d))
-	log.Printf("[INFO] Postgres Role ID: %s", d.Id())
+	if objectType.overloaded {
+		signatures, err := routineSignatures(d, names, target, meta)
+		if err != nil {
+			return "", err
+		}
+		return objectType.object + " " + strings.Join(signatures, ", "), nil
+	}
 
-	return err
+	objects := make([]string, len(names))
+	for i, name := range names {
+		objects[i] = pgsql.Format("%I.%I", schemaName, name)
+	}
+	return objectType.object + " " + strings.Join(objects, ", "), nil
 }
 
-func generateGrantID(d *schema.ResourceData) string {
-	return strings.Join([]string{
-		d.Get("role").(string), d.Get("database").(string),
-		d.Get("schema").(string), d.Get("object_type").(string),
-	}, "_")
+// routineSignatures names every overload of the routines names of the
+// grant's schema with its argument types, as a routine name alone is
+// ambiguous when it is overloaded. Routines that do not exist are named
+// without arguments, so that the statement reports them missing.
+func routineSignatures(d resourceGetter, names []string, target dataAPITarget, meta interface{}) ([]string, error) {
+	schemaName := d.Get("schema").(string)
+
+	rows, err := grantACL(d, objectType(d).acl, target, meta)
+	if err != nil {
+		return nil, fmt.Errorf("Error listing Postgres %s overloads: %#v", d.Get("object_type").(string), err)
+	}
+
+	var signatures []string
+	for _, name := range names {
+		found := false
+		for _, row := range rows {
+			if row.getString("name") == name {
+				signatures = append(signatures, pgsql.Format("%I.%I", schemaName, name)+"("+row.getString("arguments")+")")
+				found = true
+			}
+		}
+		if !found {
+			signatures = append(signatures, pgsql.Format("%I.%I", schemaName, name))
+		}
+	}
+	return signatures, nil
 }
 
-func resourceAwsRdsdataservicePostgresGrantDelete(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+// grantStatement returns the GRANT or REVOKE statement of privileges on the
+// objects named by on. Column grants apply each privilege to the columns.
+func grantStatement(d resourceGetter, action string, privileges []string, on string) string {
+	preposition := "TO"
+	if action == "REVOKE" {
+		preposition = "FROM"
+	}
+
+	if columns := setToSortedStrings(d.Get("columns").(*schema.Set)); len(columns) > 0 {
+		for i, column := range columns {
+			columns[i] = pgsql.Ident(column)
+		}
+		withColumns := make([]string, len(privileges))
+		for i, privilege := range privileges {
+			withColumns[i] = fmt.Sprintf("%s (%s)", privilege, strings.Join(columns, ", "))
+		}
+		privileges = withColumns
+	}
 
-	sql := fmt.Sprintf(
-		"REVOKE ALL PRIVILEGES ON ALL %sS IN SCHEMA %s FROM %s",
-		strings.ToUpper(d.Get("object_type").(string)),
-		pq.QuoteIdentifier(d.Get("schema").(string)),
-		pq.QuoteIdentifier(d.Get("role").(string)),
+	return fmt.Sprintf(
+		"%s %s ON %s %s %s",
+		action,
+		strings.Join(privileges, ","),
+		on,
+		preposition,
+		pgsql.Ident(d.Get("role").(string)),
 	)
+}
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
+func grantOption(withGrantOption bool) string {
+	if withGrantOption {
+		return " WITH GRANT OPTION"
 	}
+	return ""
+}
 
-	log.Printf("[DEBUG] Drop Postgres Grant: %#v", createOpts)
+func setToSortedStrings(set *schema.Set) []string {
+	list := make([]string, 0, set.Len())
+	for _, v := range set.List() {
+		list = append(list, v.(string))
+	}
+	sort.Strings(list)
+	return list
+}
+
+func generateGrantID(d *schema.ResourceData, database string) string {
+	parts := []string{
+		d.Get("role").(string), database,
+		d.Get("schema").(string), normalizeGrantObjectType(d.Get("object_type").(string)),
+	}
+	if objects := setToSortedStrings(d.Get("objects").(*schema.Set)); len(objects) > 0 {
+		parts = append(parts, strings.Join(objects, ","))
+	}
+	return strings.Join(parts, "_")
+}
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+func resourceAwsRdsdataservicePostgresGrantDelete(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
 
+	on, err := grantOn(d, target, meta)
 	if err != nil {
-		return fmt.Errorf("Error dropping Postgres Grant: %#v", err)
+		return err
+	}
+
+	if on != "" {
+		createOpts := target.statement(grantStatement(d, "REVOKE", []string{"ALL PRIVILEGES"}, on))
+
+		log.Printf("[DEBUG] Drop Postgres Grant: %#v", createOpts)
+
+		err = executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+			objects, err := grantObjectPrivileges(d, target, meta)
+			if err != nil {
+				return false, err
+			}
+			for _, object := range objects {
+				if object.privileges.Len() > 0 {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error dropping Postgres Grant: %#v", err)
+		}
 	}
 
 	d.SetId("")
 	return nil
 }
 
+// customizeDiffGrantPrivileges checks the schema, objects, columns and
+// privileges against the object type.
+func customizeDiffGrantPrivileges(diff *schema.ResourceDiff, meta interface{}) error {
+	if !diff.NewValueKnown("object_type") {
+		return nil
+	}
+	name := normalizeGrantObjectType(diff.Get("object_type").(string))
+	objectType := grantObjectTypes[name]
+
+	if diff.NewValueKnown("schema") {
+		hasSchema := diff.Get("schema").(string) != ""
+		if objectType.scope == grantScopeDatabase && hasSchema {
+			return fmt.Errorf("schema cannot be set for %s grants", name)
+		}
+		if objectType.scope != grantScopeDatabase && !hasSchema {
+			return fmt.Errorf("schema is required for %s grants", name)
+		}
+	}
+
+	if !diff.NewValueKnown("objects") || !diff.NewValueKnown("columns") {
+		return nil
+	}
+	hasObjects := diff.Get("objects").(*schema.Set).Len() > 0
+	hasColumns := diff.Get("columns").(*schema.Set).Len() > 0
+	if hasObjects && objectType.object == "" {
+		return fmt.Errorf("objects cannot be set for %s grants", name)
+	}
+	if hasColumns && objectType.columnPrivileges == nil {
+		return fmt.Errorf("columns cannot be set for %s grants", name)
+	}
+	if hasColumns && !hasObjects {
+		return fmt.Errorf("columns can only be granted on the tables listed in objects")
+	}
+
+	if !diff.NewValueKnown("privileges") {
+		return nil
+	}
+	valid := validPrivileges(diff)
+	for _, privilege := range setToSortedStrings(grantPrivileges(valid, diff.Get("privileges").(*schema.Set))) {
+		if !stringInSlice(privilege, valid) {
+			on := "a " + name
+			if hasColumns {
+				on = "columns"
+			}
+			return fmt.Errorf("privilege %s cannot be granted on %s, expected ALL or any of %s", privilege, on, strings.Join(valid, ", "))
+		}
+	}
+
+	return nil
+}
+
 func resourceAwsRdsdataservicePostgresGrantRead(d *schema.ResourceData, meta interface{}) error {
 	exists, err := checkRoleDBSchemaExists(d, meta)
 	if err != nil {
@@ -182,13 +670,19 @@ func resourceAwsRdsdataservicePostgresGrantRead(d *schema.ResourceData, meta int
 		d.SetId("")
 		return nil
 	}
+
+	// Imported IDs may spell the object type in any accepted form
+	d.Set("object_type", normalizeGrantObjectType(d.Get("object_type").(string)))
+
 	return readRolePrivileges(d, meta)
 }
 
 func checkRoleDBSchemaExists(d *schema.ResourceData, meta interface{}) (bool, error) {
+	target := resourceTarget(d, meta)
+
 	// Check the role exists
 	role := d.Get("role").(string)
-	exists, err := roleExists(role, d, meta)
+	exists, err := roleExists(role, target, meta)
 	if err != nil {
 		return false, err
 	}
@@ -198,8 +692,8 @@ func checkRoleDBSchemaExists(d *schema.ResourceData, meta interface{}) (bool, er
 	}
 
 	// Check the database exists
-	database := d.Get("database").(string)
-	exists, err = dbExists(database, d, meta)
+	database := target.withDatabase(d.Get("database").(string)).Database
+	exists, err = dbExists(database, target, meta)
 	if err != nil {
 		return false, err
 	}
@@ -208,9 +702,13 @@ func checkRoleDBSchemaExists(d *schema.ResourceData, meta interface{}) (bool, er
 		return false, nil
 	}
 
+	if objectType(d).scope == grantScopeDatabase {
+		return true, nil
+	}
+
 	// Check the schema exists (the SQL connection needs to be on the right database)
 	schema := d.Get("schema").(string)
-	exists, err = schemaExists(schema, d, meta)
+	exists, err = schemaExists(schema, target.withDatabase(database), meta)
 	if err != nil {
 		return false, err
 	}
@@ -222,48 +720,183 @@ func checkRoleDBSchemaExists(d *schema.ResourceData, meta interface{}) (bool, er
 	return true, nil
 }
 
-func readRolePrivileges(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+// grantACL runs an ACL query of the grant's object type.
+func grantACL(d resourceGetter, sql string, target dataAPITarget, meta interface{}) ([]resultRow, error) {
+	objectType := objectType(d)
+
+	if objectType.acl10 != "" && sql == objectType.acl {
+		version, err := serverVersionNum(target, meta)
+		if err != nil {
+			return nil, err
+		}
+		if version < 110000 {
+			if objectType.kind == "p" {
+				return nil, fmt.Errorf("procedure grants need PostgreSQL 11 or later, the server runs %d", version)
+			}
+			sql = objectType.acl10
+		}
+	}
 
-	// This returns, for the specified role (rolname),
-	// the list of all object of the specified type (relkind) in the specified schema (namespace)
-	// with the list of the currently applied privileges (aggregation of privilege_type)
-	//
-	// Our goal is to check that every object has the same privileges as saved in the state.
-	sql := `
-SELECT pg_class.relname, array_remove(array_agg(privilege_type), NULL)
-FROM pg_class
-JOIN pg_namespace ON pg_namespace.oid = pg_class.relnamespace
-LEFT JOIN (
-    SELECT acls.* FROM (
-        SELECT relname, relnamespace, relkind, (aclexplode(relacl)).* FROM pg_class c
-    ) as acls
-    JOIN pg_roles on grantee = pg_roles.oid
-    WHERE rolname=$1
-) privs
-USING (relname, relnamespace, relkind)
-WHERE nspname = $2 AND relkind = $3
-GROUP BY pg_class.relname;
-`
-
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-	}
-
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	parameters := []*rdsdataservice.SqlParameter{stringParameter("role", d.Get("role").(string))}
+	if objectType.scope == grantScopeDatabase {
+		parameters = append(parameters, stringParameter("database", target.Database))
+	} else {
+		parameters = append(parameters, stringParameter("schema", d.Get("schema").(string)))
+	}
+	if objectType.kind != "" {
+		parameters = append(parameters, stringParameter("kind", objectType.kind))
+	}
 
+	return queryRows(meta, target, sql, parameters...)
+}
+
+// objectPrivileges are the privileges a role holds on an object or column,
+// and of those the ones it may grant.
+type objectPrivileges struct {
+	name       string
+	privileges *schema.Set
+	grantable  *schema.Set
+}
+
+// grantObjectPrivileges returns the privileges the role holds on each
+// object, or each column, covered by the grant. Listed objects and columns
+// that do not exist hold no privileges.
+func grantObjectPrivileges(d resourceGetter, target dataAPITarget, meta interface{}) ([]objectPrivileges, error) {
+	sql := objectType(d).acl
+	columns := d.Get("columns").(*schema.Set)
+	if columns.Len() > 0 {
+		sql = columnACL
+	}
+
+	rows, err := grantACL(d, sql, target, meta)
 	if err != nil {
-		return fmt.Errorf("Error reading Postgres Database: %#v", err)
+		return nil, err
 	}
 
-	if len(output.Records) != 1 {
-		d.SetId("")
-		return nil
+	objects := d.Get("objects").(*schema.Set)
+	missing := make(map[string]bool)
+	for _, object := range objects.List() {
+		if columns.Len() == 0 {
+			missing[object.(string)] = true
+			continue
+		}
+		for _, column := range columns.List() {
+			missing[object.(string)+"."+column.(string)] = true
+		}
+	}
+
+	result := []objectPrivileges{}
+	for _, row := range rows {
+		name := row.getString("name")
+		if objects.Len() > 0 && !objects.Contains(name) {
+			continue
+		}
+		if columns.Len() > 0 {
+			if !columns.Contains(row.getString("column_name")) {
+				continue
+			}
+			name += "." + row.getString("column_name")
+		}
+		delete(missing, name)
+
+		privileges, err := row.getStringList("privileges")
+		if err != nil {
+			return nil, err
+		}
+		grantable, err := row.getStringList("grantable")
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, objectPrivileges{
+			name:       name,
+			privileges: stringSet(privileges),
+			grantable:  stringSet(grantable),
+		})
 	}
 
-	// TODO: Complete this :)
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Printf("[DEBUG] %s %s does not exist", d.Get("object_type").(string), name)
+		result = append(result, objectPrivileges{
+			name:       name,
+			privileges: stringSet(nil),
+			grantable:  stringSet(nil),
+		})
+	}
+
+	return result, nil
+}
+
+func stringSet(list []string) *schema.Set {
+	set := schema.NewSet(schema.HashString, nil)
+	for _, v := range list {
+		set.Add(v)
+	}
+	return set
+}
+
+func readRolePrivileges(d *schema.ResourceData, meta interface{}) error {
+	// Our goal is to check that every object has the same privileges as saved in the state.
+	objects, err := grantObjectPrivileges(d, resourceTarget(d, meta).withDatabase(d.Get("database").(string)), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Grant: %#v", err)
+	}
+
+	configured := grantPrivileges(validPrivileges(d), d.Get("privileges").(*schema.Set))
+	withGrantOption := d.Get("with_grant_option").(bool)
+
+	// An empty schema has nothing to compare, and the grant covers the
+	// objects created later only through default privileges. The drift of
+	// every object is kept, so that a single apply grants the privileges
+	// any object lacks and revokes those any object has in excess.
+	missing := stringSet(nil)
+	extra := stringSet(nil)
+	for _, object := range objects {
+		if !object.privileges.Equal(configured) {
+			log.Printf("[DEBUG] %s %s has privileges %v for %s, expected %v",
+				d.Get("object_type").(string), object.name, object.privileges.List(), d.Get("role").(string), configured.List())
+		}
+		missing = missing.Union(configured.Difference(object.privileges))
+		extra = extra.Union(object.privileges.Difference(configured))
+	}
+	if missing.Len() > 0 || extra.Len() > 0 {
+		d.Set("privileges", configured.Difference(missing).Union(extra))
+	}
+
+	for _, object := range objects {
+		grantable := object.privileges.Len() > 0 && object.grantable.Equal(object.privileges)
+		if grantable != withGrantOption {
+			log.Printf("[DEBUG] %s %s has privileges %v for %s, of which %v may be granted",
+				d.Get("object_type").(string), object.name, object.privileges.List(), d.Get("role").(string), object.grantable.List())
+			d.Set("with_grant_option", grantable)
+			break
+		}
+	}
 
 	return nil
 }
+
+// grantPrivileges returns the configured privileges the way the catalog
+// reports them: upper case, with ALL standing for the valid privileges and
+// TEMP expanded.
+func grantPrivileges(valid []string, configured *schema.Set) *schema.Set {
+	privileges := schema.NewSet(schema.HashString, nil)
+	for _, v := range configured.List() {
+		privilege := strings.ToUpper(v.(string))
+		if privilege == "TEMP" {
+			privilege = "TEMPORARY"
+		}
+		if privilege == "ALL" || privilege == "ALL PRIVILEGES" {
+			for _, p := range valid {
+				privileges.Add(p)
+			}
+			continue
+		}
+		privileges.Add(privilege)
+	}
+	return privileges
+}
@@ -0,0 +1,207 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func resourceAwsRdsdataservicePostgresGrantRole() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsRdsdataservicePostgresGrantRoleCreate,
+		Read:   resourceAwsRdsdataservicePostgresGrantRoleRead,
+		Update: resourceAwsRdsdataservicePostgresGrantRoleUpdate,
+		Delete: resourceAwsRdsdataservicePostgresGrantRoleDelete,
+		Importer: dataAPIImport{
+			resourceType: "rdsdataservice_postgres_grant_role",
+			database:     false,
+			names:        []string{"role", "grant_role"},
+			id: func(d *schema.ResourceData, target dataAPITarget) string {
+				return generateGrantRoleID(d)
+			},
+		}.importer(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffDataAPITarget(false),
+			customizeDiffGrantRoleCycle,
+		),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"role": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The role that is made a member of grant_role",
+			},
+			"grant_role": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The role whose membership is granted",
+			},
+			"with_admin_option": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether role may grant the membership of grant_role to others",
+			},
+			"resource_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
+			},
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
+			},
+		},
+	}
+}
+
+func resourceAwsRdsdataservicePostgresGrantRoleCreate(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
+	role := d.Get("role").(string)
+	grantRole := d.Get("grant_role").(string)
+	admin := d.Get("with_admin_option").(bool)
+
+	sql := pgsql.Format("GRANT %I TO %I", grantRole, role)
+	if admin {
+		sql += " WITH ADMIN OPTION"
+	}
+
+	createOpts := target.statement(sql)
+
+	log.Printf("[DEBUG] Create Postgres Grant Role: %#v", createOpts)
+
+	err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutCreate), func() (bool, error) {
+		granted, withAdmin, err := roleMembership(role, grantRole, target, meta)
+		return granted && withAdmin == admin, err
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error granting Postgres Role %s to %s: %#v", grantRole, role, err)
+	}
+
+	d.SetId(generateGrantRoleID(d))
+
+	return resourceAwsRdsdataservicePostgresGrantRoleRead(d, meta)
+}
+
+func generateGrantRoleID(d *schema.ResourceData) string {
+	return d.Get("role").(string) + "|" + d.Get("grant_role").(string)
+}
+
+func resourceAwsRdsdataservicePostgresGrantRoleRead(d *schema.ResourceData, meta interface{}) error {
+	granted, admin, err := roleMembership(d.Get("role").(string), d.Get("grant_role").(string), resourceTarget(d, meta), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Grant Role: %#v", err)
+	}
+
+	if !granted {
+		log.Printf("[WARN] Postgres Role %s is not a member of %s, removing from state", d.Get("role").(string), d.Get("grant_role").(string))
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("with_admin_option", admin)
+	return nil
+}
+
+func resourceAwsRdsdataservicePostgresGrantRoleUpdate(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
+	role := d.Get("role").(string)
+	grantRole := d.Get("grant_role").(string)
+	admin := d.Get("with_admin_option").(bool)
+
+	if d.HasChange("with_admin_option") {
+		sql := pgsql.Format("REVOKE ADMIN OPTION FOR %I FROM %I", grantRole, role)
+		if admin {
+			sql = pgsql.Format("GRANT %I TO %I WITH ADMIN OPTION", grantRole, role)
+		}
+
+		updateOpts := target.statement(sql)
+
+		log.Printf("[DEBUG] Update Postgres Grant Role: %#v", updateOpts)
+
+		err := executeDDL(executor, &updateOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			granted, withAdmin, err := roleMembership(role, grantRole, target, meta)
+			return granted && withAdmin == admin, err
+		})
+
+		if err != nil {
+			return fmt.Errorf("Error updating Postgres Grant Role: %#v", err)
+		}
+	}
+
+	return resourceAwsRdsdataservicePostgresGrantRoleRead(d, meta)
+}
+
+func resourceAwsRdsdataservicePostgresGrantRoleDelete(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
+	role := d.Get("role").(string)
+	grantRole := d.Get("grant_role").(string)
+
+	deleteOpts := target.statement(pgsql.Format("REVOKE %I FROM %I", grantRole, role))
+
+	log.Printf("[DEBUG] Drop Postgres Grant Role: %#v", deleteOpts)
+
+	err := executeDDL(executor, &deleteOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		granted, _, err := roleMembership(role, grantRole, target, meta)
+		return !granted, err
+	})
+
+	if err != nil {
+		return fmt.Errorf("Error revoking Postgres Role %s from %s: %#v", grantRole, role, err)
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// customizeDiffGrantRoleCycle fails the plan of a membership that would
+// make grant_role a member of itself, which PostgreSQL only rejects at apply
+// time. Memberships are looked up on the cluster, so cycles made only of
+// memberships that are not created yet are not caught.
+func customizeDiffGrantRoleCycle(diff *schema.ResourceDiff, meta interface{}) error {
+	if diff.Id() != "" && !diff.HasChange("role") && !diff.HasChange("grant_role") {
+		return nil
+	}
+	for _, key := range []string{"role", "grant_role", "resource_arn", "secret_arn"} {
+		if !diff.NewValueKnown(key) {
+			return nil
+		}
+	}
+
+	role := diff.Get("role").(string)
+	grantRole := diff.Get("grant_role").(string)
+
+	if role == grantRole {
+		return fmt.Errorf("Postgres Role %s cannot be granted to itself", role)
+	}
+
+	cycle, err := roleMemberOf(grantRole, role, resourceTarget(diff, meta), meta)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return fmt.Errorf("Postgres Role %s cannot be granted to %s: %s is already a member of %s", grantRole, role, grantRole, role)
+	}
+
+	return nil
+}
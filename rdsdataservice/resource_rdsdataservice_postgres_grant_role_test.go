@@ -0,0 +1,183 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func testPostgresGrantRoleConfig(admin bool) map[string]interface{} {
+	return map[string]interface{}{
+		"role":              "app",
+		"grant_role":        "readers",
+		"with_admin_option": admin,
+		"resource_arn":      testResourceArn,
+		"secret_arn":        testSecretArn,
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleCreate(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_auth_members", []string{"admin_option"}, testRecord(testBoolField(true)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrantRole().Schema, testPostgresGrantRoleConfig(true))
+
+	if err := resourceAwsRdsdataservicePostgresGrantRoleCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(executor.inputs) != 2 {
+		t.Fatalf("expected the grant and its read, got %d statements", len(executor.inputs))
+	}
+	if sql := aws.StringValue(executor.inputs[0].Sql); sql != `GRANT "readers" TO "app" WITH ADMIN OPTION` {
+		t.Fatalf("unexpected statement: %s", sql)
+	}
+	executor.expectParameters(t, 1, map[string]string{"role": "app", "grant_role": "readers"})
+	if d.Id() != "app|readers" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleCreateContinuesAfterTimeout(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		failOnceOn("GRANT", awserr.New(rdsdataservice.ErrCodeStatementTimeoutException, "Request timed out", nil)).
+		onRows("FROM pg_auth_members", []string{"admin_option"}, testRecord(testBoolField(false)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrantRole().Schema, testPostgresGrantRoleConfig(false))
+
+	if err := resourceAwsRdsdataservicePostgresGrantRoleCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.Id() != "app|readers" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleRead(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_auth_members", []string{"admin_option"}, testRecord(testBoolField(true)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrantRole().Schema, testPostgresGrantRoleConfig(false))
+	d.SetId("app|readers")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRoleRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !d.Get("with_admin_option").(bool) {
+		t.Fatalf("expected with_admin_option to be read back")
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleReadNotFound(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrantRole().Schema, testPostgresGrantRoleConfig(false))
+	d.SetId("app|readers")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRoleRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "" {
+		t.Fatalf("expected resource to be removed from state, got ID %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleUpdate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old, new bool
+		expected string
+	}{
+		{"grant admin option", false, true, `GRANT "readers" TO "app" WITH ADMIN OPTION`},
+		{"revoke admin option", true, false, `REVOKE ADMIN OPTION FOR "readers" FROM "app"`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				onRows("FROM pg_auth_members", []string{"admin_option"}, testRecord(testBoolField(testCase.new)))
+			client := &AWSClient{executor: executor}
+			r := resourceAwsRdsdataservicePostgresGrantRole()
+			d := testResourceDataUpdate(t, r, "app|readers", testPostgresGrantRoleConfig(testCase.old), testPostgresGrantRoleConfig(testCase.new), client)
+
+			if err := resourceAwsRdsdataservicePostgresGrantRoleUpdate(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if sql := aws.StringValue(executor.inputs[0].Sql); sql != testCase.expected {
+				t.Fatalf("got %q, expected %q", sql, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleDelete(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrantRole().Schema, testPostgresGrantRoleConfig(false))
+	d.SetId("app|readers")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRoleDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `REVOKE "readers" FROM "app"`)
+	if d.Id() != "" {
+		t.Fatalf("expected no ID, got %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantRoleCycle(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    map[string]interface{}
+		memberOf  bool
+		expectErr string
+	}{
+		{
+			name:   "no cycle",
+			config: testPostgresGrantRoleConfig(false),
+		},
+		{
+			name:      "cycle",
+			config:    testPostgresGrantRoleConfig(false),
+			memberOf:  true,
+			expectErr: "readers is already a member of app",
+		},
+		{
+			name: "self",
+			config: map[string]interface{}{
+				"role":         "app",
+				"grant_role":   "app",
+				"resource_arn": testResourceArn,
+				"secret_arn":   testSecretArn,
+			},
+			expectErr: "cannot be granted to itself",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			if testCase.memberOf {
+				executor.on("WITH RECURSIVE", testRecord(testLongField(1)))
+			}
+
+			_, err := resourceAwsRdsdataservicePostgresGrantRole().Diff(nil, terraform.NewResourceConfigRaw(testCase.config), &AWSClient{executor: executor})
+
+			if testCase.expectErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				// grant_role must not already be a member of role
+				executor.expectParameters(t, 0, map[string]string{"role": "readers", "grant_role": "app"})
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,918 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func testPostgresGrantConfig(privileges ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"role":         "app",
+		"database":     "app",
+		"schema":       "public",
+		"object_type":  "table",
+		"privileges":   privileges,
+		"resource_arn": testResourceArn,
+		"secret_arn":   testSecretArn,
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantCreate(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, testPostgresGrantConfig("SELECT"))
+
+	if err := resourceAwsRdsdataservicePostgresGrantCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+		`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "app"`,
+		"COMMIT",
+	)
+	if d.Id() != "app_app_public_table" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantCreateRollback(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		failOn("GRANT", fmt.Errorf("invalid privilege type"))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, testPostgresGrantConfig("SELECT"))
+
+	if err := resourceAwsRdsdataservicePostgresGrantCreate(d, &AWSClient{executor: executor}); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+		`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "app"`,
+		"ROLLBACK",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadRoleNotFound(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, testPostgresGrantConfig("SELECT"))
+	d.SetId("app_app_public_table")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, "SELECT 1 FROM pg_roles WHERE rolname = :name")
+	executor.expectParameters(t, 0, map[string]string{"name": "app"})
+	if d.Id() != "" {
+		t.Fatalf("expected resource to be removed from state, got ID %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadPrivileges(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+		on("FROM pg_database", testRecord(testStringField("app"))).
+		on("FROM pg_namespace WHERE", testRecord(testLongField(1)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, testPostgresGrantConfig("SELECT"))
+	d.SetId("app_app_public_table")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(executor.statements) != 4 {
+		t.Fatalf("unexpected statements: %v", executor.statements)
+	}
+	executor.expectParameters(t, 2, map[string]string{"name": "public"})
+	executor.expectParameters(t, 3, map[string]string{"role": "app", "schema": "public", "kind": "r"})
+	for _, i := range []int{2, 3} {
+		if v := aws.StringValue(executor.inputs[i].Database); v != "app" {
+			t.Fatalf("query %q ran in database %q", aws.StringValue(executor.inputs[i].Sql), v)
+		}
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadDrift(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured []interface{}
+		records    [][]*rdsdataservice.Field
+		expected   []string
+	}{
+		{
+			name:       "in sync",
+			configured: []interface{}{"select", "INSERT"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{INSERT,SELECT}")),
+				testRecord(testStringField("b"), testStringField("{SELECT,INSERT}")),
+			},
+			expected: []string{"INSERT", "select"},
+		},
+		{
+			name:       "one object diverges",
+			configured: []interface{}{"SELECT", "INSERT"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{INSERT,SELECT}")),
+				testRecord(testStringField("b"), testStringField("{SELECT}")),
+			},
+			expected: []string{"SELECT"},
+		},
+		{
+			name:       "objects lack different privileges",
+			configured: []interface{}{"SELECT", "INSERT"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{SELECT}")),
+				testRecord(testStringField("b"), testStringField("{INSERT}")),
+				testRecord(testStringField("c"), testStringField("{INSERT,SELECT}")),
+			},
+			expected: []string{},
+		},
+		{
+			name:       "objects lack and exceed privileges",
+			configured: []interface{}{"SELECT", "INSERT"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{INSERT,SELECT,UPDATE}")),
+				testRecord(testStringField("b"), testStringField("{SELECT}")),
+				testRecord(testStringField("c"), testStringField("{DELETE,INSERT,SELECT}")),
+			},
+			expected: []string{"DELETE", "SELECT", "UPDATE"},
+		},
+		{
+			name:       "revoked everywhere",
+			configured: []interface{}{"SELECT"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{}")),
+			},
+			expected: []string{},
+		},
+		{
+			name:       "all",
+			configured: []interface{}{"ALL"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{SELECT,INSERT,UPDATE,DELETE,TRUNCATE,REFERENCES,TRIGGER}")),
+			},
+			expected: []string{"ALL"},
+		},
+		{
+			name:       "empty schema",
+			configured: []interface{}{"SELECT"},
+			expected:   []string{"SELECT"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+				on("FROM pg_database", testRecord(testStringField("app"))).
+				on("FROM pg_namespace WHERE", testRecord(testLongField(1))).
+				onRows("GROUP BY pg_class.relname", []string{"name", "privileges"}, testCase.records...)
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, testPostgresGrantConfig(testCase.configured...))
+			d.SetId("app_app_public_table")
+
+			if err := resourceAwsRdsdataservicePostgresGrantRead(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if d.Id() == "" {
+				t.Fatalf("expected the grant to stay in state")
+			}
+			got := []string{}
+			for _, v := range d.Get("privileges").(*schema.Set).List() {
+				got = append(got, v.(string))
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(testCase.expected, ",") {
+				t.Fatalf("got privileges %v, expected %v", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantUpdate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old, new []interface{}
+		expected []string
+	}{
+		{
+			name: "added and removed",
+			old:  []interface{}{"SELECT", "INSERT"},
+			new:  []interface{}{"SELECT", "UPDATE", "DELETE"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE INSERT ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+				`GRANT DELETE,UPDATE ON ALL TABLES IN SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			name: "added",
+			old:  []interface{}{"SELECT"},
+			new:  []interface{}{"select", "INSERT"},
+			expected: []string{
+				"BEGIN",
+				`GRANT INSERT ON ALL TABLES IN SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			name: "narrowed from all",
+			old:  []interface{}{"ALL"},
+			new:  []interface{}{"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE TRIGGER ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			name:     "case only",
+			old:      []interface{}{"SELECT"},
+			new:      []interface{}{"select"},
+			expected: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			client := &AWSClient{executor: executor}
+			d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresGrant(), "app_app_public_table",
+				testPostgresGrantConfig(testCase.old...),
+				testPostgresGrantConfig(testCase.new...),
+				client)
+
+			if err := resourceAwsRdsdataservicePostgresGrant().Update(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectStatements(t, testCase.expected...)
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantObjectType(t *testing.T) {
+	for _, objectType := range []string{"table", "TABLE", "tables", "TABLES"} {
+		executor := &fakeExecutor{}
+		config := testPostgresGrantConfig("SELECT")
+		config["object_type"] = objectType
+		d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, config)
+
+		if err := resourceAwsRdsdataservicePostgresGrantCreate(d, &AWSClient{executor: executor}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		executor.expectStatements(t,
+			"BEGIN",
+			`REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+			`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "app"`,
+			"COMMIT",
+		)
+		if d.Id() != "app_app_public_table" {
+			t.Fatalf("%s: unexpected ID: %s", objectType, d.Id())
+		}
+	}
+
+	if _, errs := validateGrantObjectType("view", "object_type"); len(errs) == 0 {
+		t.Fatalf("expected view to be rejected")
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantDelete(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, testPostgresGrantConfig("SELECT"))
+	d.SetId("app_app_public_table")
+
+	if err := resourceAwsRdsdataservicePostgresGrantDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA "public" FROM "app"`)
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantCreateObjectTypes(t *testing.T) {
+	testCases := []struct {
+		objectType string
+		schema     string
+		privileges []interface{}
+		types      [][]*rdsdataservice.Field
+		expected   []string
+	}{
+		{
+			objectType: "database",
+			privileges: []interface{}{"CONNECT", "temp"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON DATABASE "app" FROM "app"`,
+				`GRANT CONNECT,TEMPORARY ON DATABASE "app" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			objectType: "schema",
+			schema:     "public",
+			privileges: []interface{}{"ALL"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON SCHEMA "public" FROM "app"`,
+				`GRANT CREATE,USAGE ON SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			objectType: "sequence",
+			schema:     "public",
+			privileges: []interface{}{"USAGE"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA "public" FROM "app"`,
+				`GRANT USAGE ON ALL SEQUENCES IN SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			objectType: "function",
+			schema:     "public",
+			privileges: []interface{}{"EXECUTE"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON ALL FUNCTIONS IN SCHEMA "public" FROM "app"`,
+				`GRANT EXECUTE ON ALL FUNCTIONS IN SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			objectType: "procedure",
+			schema:     "public",
+			privileges: []interface{}{"EXECUTE"},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON ALL PROCEDURES IN SCHEMA "public" FROM "app"`,
+				`GRANT EXECUTE ON ALL PROCEDURES IN SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			objectType: "type",
+			schema:     "public",
+			privileges: []interface{}{"USAGE"},
+			types: [][]*rdsdataservice.Field{
+				testRecord(testStringField("mood"), testStringField("{}")),
+				testRecord(testStringField("Point"), testStringField("{}")),
+			},
+			expected: []string{
+				"FROM pg_type",
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON TYPE "public"."mood", "public"."Point" FROM "app"`,
+				`GRANT USAGE ON TYPE "public"."mood", "public"."Point" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			objectType: "type",
+			schema:     "public",
+			privileges: []interface{}{"USAGE"},
+			expected: []string{
+				"FROM pg_type",
+				"BEGIN",
+				"COMMIT",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.objectType, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				onRows("FROM pg_type", []string{"name", "privileges"}, testCase.types...)
+			config := testPostgresGrantConfig(testCase.privileges...)
+			config["object_type"] = testCase.objectType
+			config["schema"] = testCase.schema
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, config)
+
+			if err := resourceAwsRdsdataservicePostgresGrantCreate(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			// Only the start of the listing query is compared
+			if testCase.objectType == "type" {
+				if !strings.Contains(executor.statements[0], "FROM pg_type") {
+					t.Fatalf("expected the types to be listed first, got %s", executor.statements[0])
+				}
+				executor.statements[0] = "FROM pg_type"
+			}
+			executor.expectStatements(t, testCase.expected...)
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadDatabase(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+		on("SELECT datname FROM pg_database", testRecord(testStringField("app"))).
+		onRows("LEFT JOIN LATERAL", []string{"name", "privileges"},
+			testRecord(testStringField("app"), testStringField("{CONNECT}")))
+	config := testPostgresGrantConfig("CONNECT", "CREATE")
+	config["object_type"] = "database"
+	config["schema"] = ""
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, config)
+	d.SetId("app_app__database")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// No schema to check for database grants
+	if len(executor.statements) != 3 {
+		t.Fatalf("unexpected statements: %v", executor.statements)
+	}
+	executor.expectParameters(t, 2, map[string]string{"role": "app", "database": "app"})
+	if got := d.Get("privileges").(*schema.Set); got.Len() != 1 || !got.Contains("CONNECT") {
+		t.Fatalf("unexpected privileges: %v", got.List())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadPostgresBackend(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("current_database()", []string{"database"}, testRecord(testStringField("app"))).
+		on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+		on("SELECT datname FROM pg_database", testRecord(testStringField("app"))).
+		onRows("LEFT JOIN LATERAL", []string{"name", "privileges"},
+			testRecord(testStringField("app"), testStringField("{CONNECT}")))
+	client := &AWSClient{backend: backendPostgres, executor: executor}
+	if err := client.resolveDatabase(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Neither the grant nor the provider sets a database or the ARNs
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, map[string]interface{}{
+		"role":        "app",
+		"object_type": "database",
+		"privileges":  []interface{}{"CONNECT"},
+	})
+	d.SetId("app_app__database")
+
+	if err := resourceAwsRdsdataservicePostgresGrantRead(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "app_app__database" {
+		t.Fatalf("expected the grant to stay in state, got ID %q", d.Id())
+	}
+	executor.expectParameters(t, 2, map[string]string{"name": "app"})
+	executor.expectParameters(t, 3, map[string]string{"role": "app", "database": "app"})
+	if got := d.Get("privileges").(*schema.Set); got.Len() != 1 || !got.Contains("CONNECT") {
+		t.Fatalf("unexpected privileges: %v", got.List())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadRoutines(t *testing.T) {
+	testCases := []struct {
+		name       string
+		objectType string
+		version    int64
+		acl        string
+		kind       string
+		wantErr    string
+	}{
+		{"functions", "function", 110009, routineACL, "f", ""},
+		{"functions before PostgreSQL 11", "function", 100018, routineACL10, "f", ""},
+		{"procedures", "procedure", 140005, routineACL, "p", ""},
+		{"procedures before PostgreSQL 11", "procedure", 100018, "", "", "procedure grants need PostgreSQL 11 or later"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+				on("SELECT datname FROM pg_database", testRecord(testStringField("app"))).
+				on("FROM pg_namespace WHERE", testRecord(testLongField(1))).
+				onRows("server_version_num", []string{"version"}, testRecord(testLongField(testCase.version))).
+				onRows("FROM pg_proc", []string{"name", "arguments", "privileges", "grantable"},
+					testRecord(testStringField("total"), testStringField("numeric"), testStringField("{EXECUTE}"), testStringField("{}")))
+			config := testPostgresGrantConfig("EXECUTE")
+			config["object_type"] = testCase.objectType
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, config)
+			d.SetId("app_app_public_" + testCase.objectType)
+
+			err := resourceAwsRdsdataservicePostgresGrantRead(d, &AWSClient{executor: executor})
+			if testCase.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", testCase.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if len(executor.statements) != 5 || executor.statements[4] != normalizeTestSQL(testCase.acl) {
+				t.Fatalf("unexpected statements: %v", executor.statements)
+			}
+			executor.expectParameters(t, 4, map[string]string{"role": "app", "schema": "public", "kind": testCase.kind})
+			if got := d.Get("privileges").(*schema.Set); got.Len() != 1 || !got.Contains("EXECUTE") {
+				t.Fatalf("unexpected privileges: %v", got.List())
+			}
+		})
+	}
+}
+
+func TestPostgresGrantTypeACLSkipsMultiranges(t *testing.T) {
+	// GRANT ... ON TYPE fails on the multirange types PostgreSQL 14 creates
+	// for every range type
+	if acl := grantObjectTypes["type"].acl; !strings.Contains(acl, "pg_type.typtype <> 'm'") {
+		t.Fatalf("expected the type ACL to exclude multirange types:%s", acl)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantValidation(t *testing.T) {
+	testCases := []struct {
+		name       string
+		objectType string
+		schema     string
+		privileges []interface{}
+		expectErr  string
+	}{
+		{"table", "table", "public", []interface{}{"SELECT", "all"}, ""},
+		{"database", "database", "", []interface{}{"CONNECT", "TEMP"}, ""},
+		{"database privilege on a schema", "schema", "public", []interface{}{"CONNECT"}, "privilege CONNECT cannot be granted on a schema"},
+		{"injection", "table", "public", []interface{}{"SELECT ON ALL TABLES IN SCHEMA public TO app; DROP TABLE x; --"}, "cannot be granted on a table"},
+		{"database with schema", "database", "public", []interface{}{"CONNECT"}, "schema cannot be set for database grants"},
+		{"function without schema", "function", "", []interface{}{"EXECUTE"}, "schema is required for function grants"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := testPostgresGrantConfig(testCase.privileges...)
+			config["object_type"] = testCase.objectType
+			config["schema"] = testCase.schema
+
+			_, err := resourceAwsRdsdataservicePostgresGrant().Diff(nil, terraform.NewResourceConfigRaw(config), &AWSClient{})
+
+			if testCase.expectErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantCreateObjects(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   map[string]interface{}
+		executor *fakeExecutor
+		expected []string
+		id       string
+	}{
+		{
+			name: "tables",
+			config: map[string]interface{}{
+				"objects": []interface{}{"orders", "customers", "Invoices"},
+			},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON TABLE "public"."Invoices", "public"."customers", "public"."orders" FROM "app"`,
+				`GRANT SELECT ON TABLE "public"."Invoices", "public"."customers", "public"."orders" TO "app"`,
+				"COMMIT",
+			},
+			id: "app_app_public_table_Invoices,customers,orders",
+		},
+		{
+			name: "columns",
+			config: map[string]interface{}{
+				"objects":    []interface{}{"orders"},
+				"columns":    []interface{}{"col_b", "col_a"},
+				"privileges": []interface{}{"UPDATE"},
+			},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ("col_a", "col_b") ON TABLE "public"."orders" FROM "app"`,
+				`GRANT UPDATE ("col_a", "col_b") ON TABLE "public"."orders" TO "app"`,
+				"COMMIT",
+			},
+			id: "app_app_public_table_orders",
+		},
+		{
+			name: "with grant option",
+			config: map[string]interface{}{
+				"with_grant_option": true,
+			},
+			expected: []string{
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+				`GRANT SELECT ON ALL TABLES IN SCHEMA "public" TO "app" WITH GRANT OPTION`,
+				"COMMIT",
+			},
+			id: "app_app_public_table",
+		},
+		{
+			name: "functions",
+			config: map[string]interface{}{
+				"object_type": "function",
+				"objects":     []interface{}{"refresh"},
+				"privileges":  []interface{}{"EXECUTE"},
+			},
+			executor: (&fakeExecutor{}).
+				onRows("server_version_num", []string{"version"}, testRecord(testLongField(110009))).
+				onRows("FROM pg_proc", []string{"name", "arguments"},
+					testRecord(testStringField("refresh"), testStringField(""))),
+			expected: []string{
+				"SELECT current_setting('server_version_num')::integer AS version",
+				routineACL,
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON FUNCTION "public"."refresh"() FROM "app"`,
+				`GRANT EXECUTE ON FUNCTION "public"."refresh"() TO "app"`,
+				"COMMIT",
+			},
+			id: "app_app_public_function_refresh",
+		},
+		{
+			name: "overloaded functions",
+			config: map[string]interface{}{
+				"object_type": "function",
+				"objects":     []interface{}{"refresh", "report"},
+				"privileges":  []interface{}{"EXECUTE"},
+			},
+			executor: (&fakeExecutor{}).
+				onRows("server_version_num", []string{"version"}, testRecord(testLongField(100018))).
+				onRows("FROM pg_proc", []string{"name", "arguments"},
+					testRecord(testStringField("archive"), testStringField("")),
+					testRecord(testStringField("refresh"), testStringField("full boolean")),
+					testRecord(testStringField("refresh"), testStringField("since timestamp with time zone, \"limit\" integer"))),
+			expected: []string{
+				"SELECT current_setting('server_version_num')::integer AS version",
+				routineACL10,
+				"BEGIN",
+				`REVOKE ALL PRIVILEGES ON FUNCTION "public"."refresh"(full boolean), "public"."refresh"(since timestamp with time zone, "limit" integer), "public"."report" FROM "app"`,
+				`GRANT EXECUTE ON FUNCTION "public"."refresh"(full boolean), "public"."refresh"(since timestamp with time zone, "limit" integer), "public"."report" TO "app"`,
+				"COMMIT",
+			},
+			id: "app_app_public_function_refresh,report",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := testCase.executor
+			if executor == nil {
+				executor = &fakeExecutor{}
+			}
+			config := testPostgresGrantConfig("SELECT")
+			for k, v := range testCase.config {
+				config[k] = v
+			}
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, config)
+
+			if err := resourceAwsRdsdataservicePostgresGrantCreate(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectStatements(t, testCase.expected...)
+			if d.Id() != testCase.id {
+				t.Fatalf("unexpected ID: %s", d.Id())
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantReadObjects(t *testing.T) {
+	testCases := []struct {
+		name            string
+		config          map[string]interface{}
+		columns         []string
+		records         [][]*rdsdataservice.Field
+		privileges      []string
+		withGrantOption bool
+	}{
+		{
+			name:    "listed objects only",
+			config:  map[string]interface{}{"objects": []interface{}{"a"}},
+			columns: []string{"name", "privileges", "grantable"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{SELECT}"), testStringField("{}")),
+				testRecord(testStringField("b"), testStringField("{}"), testStringField("{}")),
+			},
+			privileges: []string{"SELECT"},
+		},
+		{
+			name:    "listed object dropped",
+			config:  map[string]interface{}{"objects": []interface{}{"a", "gone"}},
+			columns: []string{"name", "privileges", "grantable"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{SELECT}"), testStringField("{}")),
+			},
+			privileges: []string{},
+		},
+		{
+			name: "columns",
+			config: map[string]interface{}{
+				"objects":    []interface{}{"orders"},
+				"columns":    []interface{}{"col_a", "col_b"},
+				"privileges": []interface{}{"UPDATE"},
+			},
+			columns: []string{"name", "column_name", "privileges", "grantable"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("orders"), testStringField("col_a"), testStringField("{UPDATE}"), testStringField("{}")),
+				testRecord(testStringField("orders"), testStringField("col_b"), testStringField("{UPDATE,SELECT}"), testStringField("{}")),
+				testRecord(testStringField("orders"), testStringField("col_c"), testStringField("{}"), testStringField("{}")),
+			},
+			privileges: []string{"SELECT", "UPDATE"},
+		},
+		{
+			name:    "grant option revoked",
+			config:  map[string]interface{}{"with_grant_option": true},
+			columns: []string{"name", "privileges", "grantable"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{SELECT}"), testStringField("{SELECT}")),
+				testRecord(testStringField("b"), testStringField("{SELECT}"), testStringField("{}")),
+			},
+			privileges: []string{"SELECT"},
+		},
+		{
+			name:    "grant option added",
+			columns: []string{"name", "privileges", "grantable"},
+			records: [][]*rdsdataservice.Field{
+				testRecord(testStringField("a"), testStringField("{SELECT}"), testStringField("{SELECT}")),
+			},
+			privileges:      []string{"SELECT"},
+			withGrantOption: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := (&fakeExecutor{}).
+				on("SELECT 1 FROM pg_roles", testRecord(testLongField(1))).
+				on("FROM pg_database", testRecord(testStringField("app"))).
+				on("FROM pg_namespace WHERE", testRecord(testLongField(1))).
+				onRows("LEFT JOIN LATERAL", testCase.columns, testCase.records...)
+			config := testPostgresGrantConfig("SELECT")
+			for k, v := range testCase.config {
+				config[k] = v
+			}
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresGrant().Schema, config)
+			d.SetId("app_app_public_table")
+
+			if err := resourceAwsRdsdataservicePostgresGrantRead(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if got := setToSortedStrings(d.Get("privileges").(*schema.Set)); strings.Join(got, ",") != strings.Join(testCase.privileges, ",") {
+				t.Fatalf("got privileges %v, expected %v", got, testCase.privileges)
+			}
+			if got := d.Get("with_grant_option").(bool); got != testCase.withGrantOption {
+				t.Fatalf("got with_grant_option %t, expected %t", got, testCase.withGrantOption)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantUpdateGrantOption(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		old, new             []interface{}
+		oldOption, newOption bool
+		expected             []string
+	}{
+		{
+			name:      "added",
+			old:       []interface{}{"SELECT"},
+			new:       []interface{}{"SELECT", "INSERT"},
+			oldOption: false,
+			newOption: true,
+			expected: []string{
+				"BEGIN",
+				`GRANT INSERT,SELECT ON ALL TABLES IN SCHEMA "public" TO "app" WITH GRANT OPTION`,
+				"COMMIT",
+			},
+		},
+		{
+			name:      "removed",
+			old:       []interface{}{"SELECT", "INSERT"},
+			new:       []interface{}{"SELECT", "UPDATE"},
+			oldOption: true,
+			newOption: false,
+			expected: []string{
+				"BEGIN",
+				`REVOKE INSERT ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+				`REVOKE GRANT OPTION FOR SELECT ON ALL TABLES IN SCHEMA "public" FROM "app"`,
+				`GRANT UPDATE ON ALL TABLES IN SCHEMA "public" TO "app"`,
+				"COMMIT",
+			},
+		},
+		{
+			name:      "kept",
+			old:       []interface{}{"SELECT"},
+			new:       []interface{}{"SELECT", "UPDATE"},
+			oldOption: true,
+			newOption: true,
+			expected: []string{
+				"BEGIN",
+				`GRANT UPDATE ON ALL TABLES IN SCHEMA "public" TO "app" WITH GRANT OPTION`,
+				"COMMIT",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			client := &AWSClient{executor: executor}
+			old := testPostgresGrantConfig(testCase.old...)
+			old["with_grant_option"] = testCase.oldOption
+			new := testPostgresGrantConfig(testCase.new...)
+			new["with_grant_option"] = testCase.newOption
+			d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresGrant(), "app_app_public_table", old, new, client)
+
+			if err := resourceAwsRdsdataservicePostgresGrant().Update(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectStatements(t, testCase.expected...)
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresGrantValidationObjects(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    map[string]interface{}
+		expectErr string
+	}{
+		{
+			name: "column privileges",
+			config: map[string]interface{}{
+				"objects":    []interface{}{"orders"},
+				"columns":    []interface{}{"col_a"},
+				"privileges": []interface{}{"SELECT", "ALL"},
+			},
+		},
+		{
+			name: "table privilege on columns",
+			config: map[string]interface{}{
+				"objects":    []interface{}{"orders"},
+				"columns":    []interface{}{"col_a"},
+				"privileges": []interface{}{"DELETE"},
+			},
+			expectErr: "privilege DELETE cannot be granted on columns",
+		},
+		{
+			name:      "columns without objects",
+			config:    map[string]interface{}{"columns": []interface{}{"col_a"}},
+			expectErr: "columns can only be granted on the tables listed in objects",
+		},
+		{
+			name: "columns of a sequence",
+			config: map[string]interface{}{
+				"object_type": "sequence",
+				"objects":     []interface{}{"ids"},
+				"columns":     []interface{}{"col_a"},
+			},
+			expectErr: "columns cannot be set for sequence grants",
+		},
+		{
+			name: "objects of a schema",
+			config: map[string]interface{}{
+				"object_type": "schema",
+				"objects":     []interface{}{"public"},
+				"privileges":  []interface{}{"USAGE"},
+			},
+			expectErr: "objects cannot be set for schema grants",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := testPostgresGrantConfig("SELECT")
+			for k, v := range testCase.config {
+				config[k] = v
+			}
+
+			_, err := resourceAwsRdsdataservicePostgresGrant().Diff(nil, terraform.NewResourceConfigRaw(config), &AWSClient{})
+
+			if testCase.expectErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.expectErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.expectErr, err)
+			}
+		})
+	}
+}
@@ -3,11 +3,14 @@ package rdsdataservice
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
 
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
 )
 
 func resourceAwsRdsdataservicePostgresRole() *schema.Resource {
@@ -17,15 +20,28 @@ func resourceAwsRdsdataservicePostgresRole() *schema.Resource {
 		Update: resourceAwsRdsdataservicePostgresRoleUpdate,
 		Delete: resourceAwsRdsdataservicePostgresRoleDelete,
 		Exists: resourceAwsRdsdataservicePostgresRoleExists,
-		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+		Importer: dataAPIImport{
+			resourceType: "rdsdataservice_postgres_role",
+			database:     false,
+			names:        []string{"name"},
+		}.importer(),
+		CustomizeDiff: customdiff.All(
+			customizeDiffDataAPITarget(false),
+			customizeDiffRolePasswordSecret,
+		),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
 			"name": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The PostgreSQL role Name.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The PostgreSQL role Name.",
 			},
 			"login": {
 				Type:        schema.TypeBool,
@@ -52,10 +68,27 @@ func resourceAwsRdsdataservicePostgresRole() *schema.Resource {
 				Description: "Determine whether this role will be permitted to create new roles.",
 			},
 			"password": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				Sensitive:     true,
+				ConflictsWith: []string{"password_secret_arn"},
+				ValidateFunc:  validateNoNUL,
+				Description:   "Sets the role's password.",
+			},
+			"password_secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of a Secrets Manager secret holding the role's password. The password is read at apply time and never stored in state.",
+			},
+			"password_secret_key": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Sensitive:   true,
-				Description: "Sets the role's password.",
+				Description: "The key of the password in a JSON secret. The whole secret string is the password if unset.",
+			},
+			"password_secret_version": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the secret version the password was last set from.",
 			},
 			"roles": {
 				Type:        schema.TypeSet,
@@ -63,12 +96,15 @@ func resourceAwsRdsdataservicePostgresRole() *schema.Resource {
 				Elem:        &schema.Schema{Type: schema.TypeString},
 				Set:         schema.HashString,
 				MinItems:    0,
+				Deprecated:  "has no effect, use the rdsdataservice_postgres_grant_role resource instead",
 				Description: "Role(s) to grant to this new role.",
 			},
 			"rolename": {
-				Type:        schema.TypeString,
-				Optional:    true,
-				Description: "Role Name to grant for the new role.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Deprecated:   "use the rdsdataservice_postgres_grant_role resource instead",
+				ValidateFunc: validateNoNUL,
+				Description:  "Role Name to grant for the new role.",
 			},
 			"superuser": {
 				Type:        schema.TypeBool,
@@ -76,183 +112,189 @@ func resourceAwsRdsdataservicePostgresRole() *schema.Resource {
 				Default:     false,
 				Description: `Determine whether the new role is a "superuser".`,
 			},
-			"secret_arn": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "The PostgreSQL database name to connect to.",
+			"replication": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Determine whether the role is allowed to initiate streaming replication.",
+			},
+			"bypass_row_level_security": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Determine whether the role bypasses every row-level security policy.",
+			},
+			"connection_limit": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      -1,
+				ValidateFunc: validation.IntAtLeast(-1),
+				Description:  "How many concurrent connections the role can make. -1 means no limit.",
+			},
+			"valid_until": {
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "infinity",
+				ValidateFunc:     validateRoleValidUntil,
+				DiffSuppressFunc: suppressEquivalentRoleValidUntil,
+				Description:      "An RFC 3339 timestamp after which the role's password is no longer valid, or infinity.",
+			},
+			"settings": {
+				Type:         schema.TypeMap,
+				Optional:     true,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				ValidateFunc: validateNoNUL,
+				Description:  "The configuration parameters set for the role in every database, e.g. statement_timeout.",
+			},
+			"database_settings": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"database": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateNoNUL,
+							Description:  "The database the settings apply in.",
+						},
+						"settings": {
+							Type:         schema.TypeMap,
+							Required:     true,
+							Elem:         &schema.Schema{Type: schema.TypeString},
+							ValidateFunc: validateNoNUL,
+							Description:  "The configuration parameters set for the role in the database.",
+						},
+					},
+				},
+				Description: "The configuration parameters set for the role in a single database, which override settings.",
 			},
 			"resource_arn": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				Default:     "postgres",
-				Description: "The PostgreSQL database name to connect to.",
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
+			},
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
 			},
 		},
 	}
 }
 
 func resourceAwsRdsdataservicePostgresRoleCreate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
 
-	name := ""
-	if attr, ok := d.GetOk("name"); ok {
-		name = fmt.Sprintf(" %s ", attr.(string))
-	}
-	password := ""
-	if attr, ok := d.GetOk("password"); ok {
-		password = fmt.Sprintf(" ENCRYPTED PASSWORD '%s' ", attr.(string))
-	}
-	superuser := ""
-	if attr, ok := d.GetOk("superuser"); ok {
-		if attr.(bool) {
-			superuser = fmt.Sprintf(" SUPERUSER ")
-		} else {
-			superuser = fmt.Sprintf(" NOSUPERUSER ")
-		}
+	name := pgsql.Ident(d.Get("name").(string))
+
+	options := roleOptions(d, false)
+	passwordOption, passwordVersion, err := rolePasswordOption(d, meta, false)
+	if err != nil {
+		return fmt.Errorf("Error creating Postgres Role: %s", err)
 	}
-	createrole := ""
-	if attr, ok := d.GetOk("create_role"); ok {
-		if attr.(bool) {
-			createrole = fmt.Sprintf(" CREATEROLE ")
-		} else {
-			createrole = fmt.Sprintf(" NOCREATEROLE ")
-		}
+	if passwordOption != "" {
+		options = append(options, passwordOption)
 	}
 
-	createdatabase := ""
-	if attr, ok := d.GetOk("create_database"); ok {
-		if attr.(bool) {
-			createrole = fmt.Sprintf(" CREATEDB ")
-		} else {
-			createrole = fmt.Sprintf(" NOCREATEDB ")
-		}
-	}
+	sql := fmt.Sprintf("CREATE ROLE %s WITH %s", name, strings.Join(options, " "))
 
-	inherit := ""
-	if attr, ok := d.GetOk("inherit"); ok {
-		if attr.(bool) {
-			inherit = fmt.Sprintf(" INHERIT ")
-		} else {
-			inherit = fmt.Sprintf(" NOINHERIT ")
+	err = withTransaction(executor, target, func(transactionID *string) error {
+		createOpts := target.statement(sql)
+		createOpts.TransactionId = transactionID
+
+		// The statement is not logged, as it may contain the password
+		log.Printf("[DEBUG] Create Postgres Role: %s", d.Get("name").(string))
+
+		if err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutCreate), statementCompleted(executor, target, transactionID)); err != nil {
+			return fmt.Errorf("Error creating Postgres Role: %#v", err)
 		}
-	}
 
-	login := ""
-	if attr, ok := d.GetOk("login"); ok {
-		if attr.(bool) {
-			login = fmt.Sprintf(" LOGIN ")
-		} else {
-			login = fmt.Sprintf(" NOLOGIN ")
+		settings := settingsStatements(pgsql.Format("ALTER ROLE %I", d.Get("name").(string)), nil, d.Get("settings").(map[string]interface{}))
+		settings = append(settings, roleDatabaseSettingsStatements(d.Get("name").(string), nil, roleDatabaseSettings(d.Get("database_settings")))...)
+		if err := alterSettings(executor, target, transactionID, settings, d.Timeout(schema.TimeoutCreate)); err != nil {
+			return fmt.Errorf("Error setting Postgres Role settings: %#v", err)
 		}
-	}
-	rolename := ""
-	if attr, ok := d.GetOk("rolename"); ok {
-		rolename = fmt.Sprintf(" %s ", attr.(string))
-	} else {
-		rolename = fmt.Sprintf("root")
-	}
-
-	sql := fmt.Sprintf("CREATE ROLE %s WITH %s %s %s %s %s %s;",
-		name,
-		login,
-		password,
-		superuser,
-		createrole,
-		createdatabase,
-		inherit,
-	)
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-	}
+		rolename, ok := d.GetOk("rolename")
+		if !ok {
+			return nil
+		}
 
-	log.Printf("[DEBUG] Create Postgres Role: %#v", createOpts)
+		createOptsGrant := target.statement(fmt.Sprintf("GRANT %s to %s;", name, pgsql.Ident(rolename.(string))))
+		createOptsGrant.TransactionId = transactionID
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		log.Printf("[DEBUG] Grant Postgres Role: %#v", createOptsGrant)
 
-	if err != nil {
-		return fmt.Errorf("Error creating Postgres Role: %#v", err)
-	}
+		if err := executeDDL(executor, &createOptsGrant, d.Timeout(schema.TimeoutCreate), statementCompleted(executor, target, transactionID)); err != nil {
+			return fmt.Errorf("Error granting Postgres Role: %#v", err)
+		}
 
-	sqlgrant := fmt.Sprintf("GRANT %s to %s;",
-		name,
-		rolename,
-	)
+		return nil
+	})
 
-	createOptsGrant := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sqlgrant),
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[DEBUG] Grant Postgres Role: %#v", createOptsGrant)
-
-	_, errgrant := rdsdataserviceconn.ExecuteStatement(&createOptsGrant)
-
-	if errgrant != nil {
-		return fmt.Errorf("Error granting Postgres Role: %#v", errgrant)
-	}
 	d.SetId(d.Get("name").(string))
+	d.Set("password_secret_version", passwordVersion)
 	log.Printf("[INFO] Postgres Role ID: %s", d.Id())
 
 	return err
 }
 
 func resourceAwsRdsdataservicePostgresRoleDelete(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
-
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
 
-	sqldropowner := fmt.Sprintf("REASSIGN OWNED BY %s TO root;",
-		d.Get("name").(string))
-
-	createOptsdropowner := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sqldropowner),
+	name := d.Get("name").(string)
+	user, err := currentUser(target, meta)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[DEBUG] Drop Postgres Role: %#v", createOptsdropowner)
-
-	_, errdropowner := rdsdataserviceconn.ExecuteStatement(&createOptsdropowner)
-
-	if errdropowner != nil {
-		return fmt.Errorf("Error dropping Postgres Role: %#v", errdropowner)
+	// REASSIGN OWNED needs the privileges of the role, which only
+	// superusers have without being a member of it. The membership goes
+	// away with the role. It is not needed when the current user is
+	// already a member, and PostgreSQL refuses it when the role is a
+	// member of the current user, as is common for group roles.
+	member, err := roleMemberOf(user, name, target, meta)
+	if err != nil {
+		return err
 	}
-
-	sqldropownerend := fmt.Sprintf("DROP OWNED BY %s;",
-		d.Get("name").(string))
-
-	createOptsdropownerend := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sqldropownerend),
+	circular, err := roleMemberOf(name, user, target, meta)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[DEBUG] Drop Postgres Role: %#v", createOptsdropownerend)
-
-	_, errdropownerend := rdsdataserviceconn.ExecuteStatement(&createOptsdropownerend)
-
-	if errdropownerend != nil {
-		return fmt.Errorf("Error dropping Postgres Role: %#v", errdropownerend)
+	statements := []string{}
+	if !member && !circular {
+		statements = append(statements, pgsql.Format("GRANT %I TO CURRENT_USER;", name))
 	}
+	statements = append(statements,
+		pgsql.Format("REASSIGN OWNED BY %I TO CURRENT_USER;", name),
+		pgsql.Format("DROP OWNED BY %I;", name),
+		pgsql.Format("DROP ROLE %I", name),
+	)
 
-	sql := fmt.Sprintf("DROP ROLE %s",
-		d.Get("name").(string))
+	err = withTransaction(executor, target, func(transactionID *string) error {
+		for _, sql := range statements {
+			createOpts := target.statement(sql)
+			createOpts.TransactionId = transactionID
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-	}
+			log.Printf("[DEBUG] Drop Postgres Role: %#v", createOpts)
 
-	log.Printf("[DEBUG] Drop Postgres Role: %#v", createOpts)
+			if err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutDelete), statementCompleted(executor, target, transactionID)); err != nil {
+				return fmt.Errorf("Error dropping Postgres Role: %#v", err)
+			}
+		}
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		return nil
+	})
 
 	if err != nil {
-		return fmt.Errorf("Error dropping Postgres Role: %#v", err)
+		return err
 	}
 
 	d.SetId("")
@@ -260,20 +302,9 @@ func resourceAwsRdsdataservicePostgresRoleDelete(d *schema.ResourceData, meta in
 }
 
 func resourceAwsRdsdataservicePostgresRoleExists(d *schema.ResourceData, meta interface{}) (bool, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
-
-	sql := fmt.Sprintf("SELECT rolname FROM pg_catalog.pg_roles WHERE rolname='%s'",
-		d.Get("name").(string))
-
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-	}
-
-	log.Printf("[DEBUG] Check Postgres Role exists: %#v", createOpts)
-
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	output, err := queryCatalog(meta, resourceTarget(d, meta),
+		"SELECT rolname FROM pg_catalog.pg_roles WHERE rolname = :name",
+		stringParameter("name", d.Get("name").(string)))
 
 	if err != nil {
 		return false, fmt.Errorf("Error checking Postgres Role exists: %#v", err)
@@ -287,98 +318,258 @@ func resourceAwsRdsdataservicePostgresRoleExists(d *schema.ResourceData, meta in
 }
 
 func resourceAwsRdsdataservicePostgresRoleRead(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
-
-	sql := fmt.Sprintf("SELECT rolname, rolsuper, rolinherit, rolcreaterole, rolcreatedb, rolcanlogin FROM pg_catalog.pg_roles WHERE rolname='%s';",
-		d.Get("name").(string),
-	)
-
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-	}
-
-	log.Printf("[DEBUG] Read Postgres Role: %#v", createOpts)
-
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	rows, err := queryRows(meta, resourceTarget(d, meta),
+		"SELECT rolname, rolsuper, rolinherit, rolcreaterole, rolcreatedb, rolcanlogin, rolreplication, rolbypassrls, rolconnlimit, "+
+			`CASE WHEN isfinite(rolvaliduntil) THEN to_char(rolvaliduntil AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') ELSE COALESCE(rolvaliduntil::text, 'infinity') END AS rolvaliduntil `+
+			"FROM pg_catalog.pg_roles WHERE rolname = :name;",
+		stringParameter("name", d.Get("name").(string)))
 
 	if err != nil {
 		return fmt.Errorf("Error reading Postgres Role: %#v", err)
 	}
 
-	if len(output.Records) != 1 {
+	if len(rows) != 1 {
 		d.SetId("")
 		return nil
 	}
 
-	d.Set("name", output.Records[0][0].StringValue)
-	d.Set("rolsuper", output.Records[0][1].StringValue)
-	d.Set("rolinherit", output.Records[0][1].StringValue)
-	d.Set("rolcreaterole", output.Records[0][1].StringValue)
-	d.Set("rolcreatedb", output.Records[0][1].StringValue)
-	d.Set("rolcanlogin", output.Records[0][1].StringValue)
+	role := rows[0]
+	d.Set("name", role.getString("rolname"))
+	d.Set("superuser", role.getBool("rolsuper"))
+	d.Set("inherit", role.getBool("rolinherit"))
+	d.Set("create_role", role.getBool("rolcreaterole"))
+	d.Set("create_database", role.getBool("rolcreatedb"))
+	d.Set("login", role.getBool("rolcanlogin"))
+	d.Set("replication", role.getBool("rolreplication"))
+	d.Set("bypass_row_level_security", role.getBool("rolbypassrls"))
+	d.Set("connection_limit", role.getInt64("rolconnlimit"))
+	d.Set("valid_until", role.getString("rolvaliduntil"))
+
+	settings, err := readSettings(role.getString("rolname"), "", resourceTarget(d, meta), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Role: %s", err)
+	}
+	d.Set("settings", settingsState(d.Get("settings").(map[string]interface{}), settings))
+
+	databaseSettings, err := readRoleDatabaseSettings(role.getString("rolname"), resourceTarget(d, meta), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Role: %s", err)
+	}
+	d.Set("database_settings", roleDatabaseSettingsState(roleDatabaseSettings(d.Get("database_settings")), databaseSettings))
 
 	// TODO: password
 
 	d.SetId(d.Get("name").(string))
-	return err
+	return nil
 }
 
 func resourceAwsRdsdataservicePostgresRoleUpdate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
 
-	// TODO: run this in transaction
+	options := roleOptions(d, true)
+	passwordOption, passwordVersion, err := rolePasswordOption(d, meta, true)
+	if err != nil {
+		return fmt.Errorf("Error updating Postgres Role: %s", err)
+	}
+	if passwordOption != "" {
+		options = append(options, passwordOption)
+	}
 
-	if d.HasChange("name") {
-		oraw, nraw := d.GetChange("name")
-		o := oraw.(string)
-		n := nraw.(string)
-		if n == "" {
-			return fmt.Errorf("Error setting role Name to an empty string")
-		}
+	err = withTransaction(executor, target, func(transactionID *string) error {
+		if d.HasChange("name") {
+			oraw, nraw := d.GetChange("name")
+			o := oraw.(string)
+			n := nraw.(string)
+			if n == "" {
+				return fmt.Errorf("Error setting role Name to an empty string")
+			}
 
-		sql := fmt.Sprintf("ALTER ROLE %s RENAME TO %s", o, n)
+			sql := pgsql.Format("ALTER ROLE %I RENAME TO %I", o, n)
 
-		createOpts := rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
+			createOpts := target.statement(sql)
+			createOpts.TransactionId = transactionID
+
+			log.Printf("[DEBUG] Update Postgres Role name: %#v", createOpts)
+
+			err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), statementCompleted(executor, target, transactionID))
+
+			if err != nil {
+				return fmt.Errorf("Error updating Postgres Role name: %#v", err)
+			}
+		}
+		if d.HasChange("settings") {
+			o, n := d.GetChange("settings")
+			settings := settingsStatements(pgsql.Format("ALTER ROLE %I", d.Get("name").(string)), o.(map[string]interface{}), n.(map[string]interface{}))
+			if err := alterSettings(executor, target, transactionID, settings, d.Timeout(schema.TimeoutUpdate)); err != nil {
+				return fmt.Errorf("Error updating Postgres Role settings: %#v", err)
+			}
+		}
+		if d.HasChange("database_settings") {
+			o, n := d.GetChange("database_settings")
+			settings := roleDatabaseSettingsStatements(d.Get("name").(string), roleDatabaseSettings(o), roleDatabaseSettings(n))
+			if err := alterSettings(executor, target, transactionID, settings, d.Timeout(schema.TimeoutUpdate)); err != nil {
+				return fmt.Errorf("Error updating Postgres Role database settings: %#v", err)
+			}
 		}
+		if len(options) == 0 {
+			return nil
+		}
+
+		sql := pgsql.Format("ALTER ROLE %I WITH ", d.Get("name").(string)) + strings.Join(options, " ")
 
-		log.Printf("[DEBUG] Update Postgres Role name: %#v", createOpts)
+		createOpts := target.statement(sql)
+		createOpts.TransactionId = transactionID
 
-		_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		// The statement is not logged, as it may contain the password
+		log.Printf("[DEBUG] Update Postgres Role: %s", d.Get("name").(string))
+
+		err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), statementCompleted(executor, target, transactionID))
 
 		if err != nil {
-			return fmt.Errorf("Error updating Postgres Role name: %#v", err)
-		}
-		d.SetId(n)
-	}
-	// TODO: Store secret arn for role in tfstate
-	if d.HasChange("login") {
-		login := d.Get("login").(bool)
-		tok := "NOLOGIN"
-		if login {
-			tok = "LOGIN"
+			return fmt.Errorf("Error updating Postgres Role: %#v", err)
 		}
 
-		sql := fmt.Sprintf("ALTER ROLE %s WITH %s", d.Get("name").(string), tok)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("name").(string))
+	d.Set("password_secret_version", passwordVersion)
+	return nil
+}
+
+// roleFlags are the boolean role attributes and their CREATE ROLE options.
+var roleFlags = []struct {
+	attribute string
+	enabled   string
+	disabled  string
+}{
+	{"login", "LOGIN", "NOLOGIN"},
+	{"superuser", "SUPERUSER", "NOSUPERUSER"},
+	{"create_database", "CREATEDB", "NOCREATEDB"},
+	{"create_role", "CREATEROLE", "NOCREATEROLE"},
+	{"inherit", "INHERIT", "NOINHERIT"},
+	{"replication", "REPLICATION", "NOREPLICATION"},
+	{"bypass_row_level_security", "BYPASSRLS", "NOBYPASSRLS"},
+}
+
+// roleOptions returns the options of a CREATE ROLE or ALTER ROLE ... WITH
+// statement setting the role attributes of d. When changedOnly is set only
+// changed attributes are included: PostgreSQL requires a superuser to name
+// SUPERUSER, REPLICATION or BYPASSRLS in ALTER ROLE at all, even unchanged.
+func roleOptions(d *schema.ResourceData, changedOnly bool) []string {
+	include := func(attribute string) bool {
+		return !changedOnly || d.HasChange(attribute)
+	}
 
-		createOpts := rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
+	var options []string
+	for _, flag := range roleFlags {
+		if !include(flag.attribute) {
+			continue
+		}
+		if d.Get(flag.attribute).(bool) {
+			options = append(options, flag.enabled)
+		} else {
+			options = append(options, flag.disabled)
 		}
+	}
 
-		log.Printf("[DEBUG] Update Postgres Role login: %#v", createOpts)
+	if include("connection_limit") {
+		options = append(options, fmt.Sprintf("CONNECTION LIMIT %d", d.Get("connection_limit").(int)))
+	}
 
-		_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	// infinity is the default, so it is only spelled out to reset a date
+	if validUntil := d.Get("valid_until").(string); changedOnly && d.HasChange("valid_until") || !changedOnly && validUntil != "infinity" {
+		options = append(options, pgsql.Format("VALID UNTIL %L", validUntil))
+	}
+
+	return options
+}
 
+// rolePasswordOption returns the option setting the role password, taken
+// from password_secret_arn if set, and the ID of the secret version it came
+// from. When changedOnly is set it is empty unless the password changed.
+func rolePasswordOption(d *schema.ResourceData, meta interface{}, changedOnly bool) (string, string, error) {
+	// Renaming a role clears an MD5 password, as the name is its salt
+	if changedOnly && !d.HasChanges("password", "password_secret_arn", "password_secret_key", "password_secret_version", "name") {
+		return "", d.Get("password_secret_version").(string), nil
+	}
+
+	password, version := d.Get("password").(string), ""
+	if secretArn := d.Get("password_secret_arn").(string); secretArn != "" {
+		var err error
+		password, version, err = secretValue(meta, secretArn, d.Get("password_secret_key").(string))
 		if err != nil {
-			return fmt.Errorf("Error updating Postgres Role login: %#v", err)
+			return "", "", err
 		}
 	}
 
+	switch {
+	case password != "":
+		return pgsql.Format("ENCRYPTED PASSWORD %L", password), version, nil
+	case changedOnly && d.HasChanges("password", "password_secret_arn"):
+		return "PASSWORD NULL", version, nil
+	}
+	return "", version, nil
+}
+
+// customizeDiffRolePasswordSecret plans a password change when the current
+// version of password_secret_arn is not the one last applied, so rotated
+// secrets are picked up. The password itself is only read at apply time.
+func customizeDiffRolePasswordSecret(diff *schema.ResourceDiff, meta interface{}) error {
+	if !diff.NewValueKnown("password_secret_arn") {
+		return diff.SetNewComputed("password_secret_version")
+	}
+
+	secretArn := diff.Get("password_secret_arn").(string)
+	if secretArn == "" {
+		if diff.Get("password_secret_key").(string) != "" {
+			return fmt.Errorf("password_secret_key can only be set with password_secret_arn")
+		}
+		if diff.Get("password_secret_version").(string) != "" {
+			return diff.SetNew("password_secret_version", "")
+		}
+		return nil
+	}
+
+	version, err := currentSecretVersion(meta, secretArn)
+	if err != nil {
+		return err
+	}
+	if version != diff.Get("password_secret_version").(string) || diff.HasChange("password_secret_arn") {
+		return diff.SetNewComputed("password_secret_version")
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func validateRoleValidUntil(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+	if value == "infinity" {
+		return
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		errors = append(errors, fmt.Errorf("%q must be an RFC 3339 timestamp or infinity, got %q", k, value))
+	}
+	return
+}
+
+// suppressEquivalentRoleValidUntil ignores differences in how the same
+// instant is written, as it is read back in UTC.
+func suppressEquivalentRoleValidUntil(k, old, new string, d *schema.ResourceData) bool {
+	if old == new {
+		return true
+	}
+	o, err := time.Parse(time.RFC3339, old)
+	if err != nil {
+		return false
+	}
+	n, err := time.Parse(time.RFC3339, new)
+	if err != nil {
+		return false
+	}
+	return o.Equal(n)
+}
@@ -0,0 +1,250 @@
+package rdsdataservice
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/customdiff"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+// rolePasswordCharacters leaves out the characters RDS does not allow in
+// master passwords: /, @, " and space.
+const rolePasswordCharacters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789!#$%&*()-_=+[]{}<>:;,.?~^"
+
+func resourceAwsRdsdataservicePostgresRoleCredentials() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceAwsRdsdataservicePostgresRoleCredentialsCreate,
+		Read:   resourceAwsRdsdataservicePostgresRoleCredentialsRead,
+		Update: resourceAwsRdsdataservicePostgresRoleCredentialsUpdate,
+		Delete: resourceAwsRdsdataservicePostgresRoleCredentialsDelete,
+		CustomizeDiff: customdiff.All(
+			customizeDiffDataAPITarget(false),
+			func(diff *schema.ResourceDiff, meta interface{}) error {
+				_, err := secretsManager(meta)
+				return err
+			},
+		),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"role": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The role to generate a password for.",
+			},
+			"credentials_secret_arn": {
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				Description: "The ARN of the Secrets Manager secret the credentials are written to.",
+			},
+			"host": {
+				Type:        schema.TypeString,
+				Required:    true,
+				Description: "The host written to the secret.",
+			},
+			"port": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				Default:     5432,
+				Description: "The port written to the secret.",
+			},
+			"dbname": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The database written to the secret.",
+			},
+			"password_length": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      32,
+				ValidateFunc: validation.IntBetween(16, 128),
+				Description:  "The length of the generated password.",
+			},
+			"rotation_trigger": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Any change to this value generates a new password.",
+			},
+			"secret_version_id": {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "The ID of the secret version holding the current password.",
+			},
+			"resource_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
+			},
+			"secret_arn": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
+			},
+		},
+	}
+}
+
+func resourceAwsRdsdataservicePostgresRoleCredentialsCreate(d *schema.ResourceData, meta interface{}) error {
+	if err := setRoleCredentials(d, meta, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("role").(string) + "|" + d.Get("credentials_secret_arn").(string))
+	return resourceAwsRdsdataservicePostgresRoleCredentialsRead(d, meta)
+}
+
+func resourceAwsRdsdataservicePostgresRoleCredentialsRead(d *schema.ResourceData, meta interface{}) error {
+	exists, err := roleExists(d.Get("role").(string), resourceTarget(d, meta), meta)
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Role Credentials: %#v", err)
+	}
+	if !exists {
+		log.Printf("[WARN] Postgres Role %s not found, removing its credentials from state", d.Get("role").(string))
+		d.SetId("")
+		return nil
+	}
+
+	secretArn := d.Get("credentials_secret_arn").(string)
+	current, err := secretVersion(meta, secretArn, secretStageCurrent)
+	if isAWSErr(err, secretsmanager.ErrCodeResourceNotFoundException, "") {
+		log.Printf("[WARN] Secret %s not found, removing Postgres Role Credentials from state", secretArn)
+		d.SetId("")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error reading Postgres Role Credentials: %#v", err)
+	}
+
+	// A version written outside Terraform may not hold the role's password
+	if current != d.Get("secret_version_id").(string) {
+		log.Printf("[WARN] Secret %s was changed outside Terraform (current version %s)", secretArn, current)
+	}
+	d.Set("secret_version_id", current)
+
+	return nil
+}
+
+func resourceAwsRdsdataservicePostgresRoleCredentialsUpdate(d *schema.ResourceData, meta interface{}) error {
+	// The password is in neither the state nor the configuration, so any
+	// change writes a new one
+	if err := setRoleCredentials(d, meta, d.Timeout(schema.TimeoutUpdate)); err != nil {
+		return err
+	}
+
+	return resourceAwsRdsdataservicePostgresRoleCredentialsRead(d, meta)
+}
+
+func resourceAwsRdsdataservicePostgresRoleCredentialsDelete(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
+	role := d.Get("role").(string)
+
+	exists, err := roleExists(role, target, meta)
+	if err != nil {
+		return fmt.Errorf("Error deleting Postgres Role Credentials: %#v", err)
+	}
+
+	// The secret is left alone, but its password stops working
+	if exists {
+		createOpts := target.statement(pgsql.Format("ALTER ROLE %I WITH PASSWORD NULL", role))
+
+		log.Printf("[DEBUG] Remove Postgres Role password: %#v", createOpts)
+
+		if err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutDelete), nil); err != nil {
+			return fmt.Errorf("Error removing Postgres Role password: %#v", err)
+		}
+	}
+
+	d.SetId("")
+	return nil
+}
+
+// setRoleCredentials generates a password, stores it in the credentials
+// secret and sets it on the role. The secret version is written first and
+// only promoted to AWSCURRENT once the role has the password, so clients
+// never read a password the database does not accept yet.
+func setRoleCredentials(d *schema.ResourceData, meta interface{}, timeout time.Duration) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta)
+	role := d.Get("role").(string)
+	secretArn := d.Get("credentials_secret_arn").(string)
+
+	password, err := generatePassword(d.Get("password_length").(int))
+	if err != nil {
+		return fmt.Errorf("Error generating Postgres Role password: %s", err)
+	}
+
+	credentials := map[string]interface{}{
+		"engine":   "postgres",
+		"username": role,
+		"password": password,
+		"host":     d.Get("host").(string),
+		"port":     d.Get("port").(int),
+	}
+	if v := d.Get("dbname").(string); v != "" {
+		credentials["dbname"] = v
+	}
+	value, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	versionID, err := putSecretVersion(meta, secretArn, string(value))
+	if err != nil {
+		return err
+	}
+
+	err = withTransaction(executor, target, func(transactionID *string) error {
+		createOpts := target.statement(pgsql.Format("ALTER ROLE %I WITH ENCRYPTED PASSWORD %L", role, password))
+		createOpts.TransactionId = transactionID
+
+		// The statement is not logged, as it contains the password
+		log.Printf("[DEBUG] Set Postgres Role password: %s", role)
+
+		if err := executeDDL(executor, &createOpts, timeout, statementCompleted(executor, target, transactionID)); err != nil {
+			return fmt.Errorf("Error setting Postgres Role password: %#v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := promoteSecretVersion(meta, secretArn, versionID); err != nil {
+		return err
+	}
+
+	d.Set("secret_version_id", versionID)
+	return nil
+}
+
+// generatePassword returns a random password of length characters.
+func generatePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(rolePasswordCharacters)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = rolePasswordCharacters[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,240 @@
+package rdsdataservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+const testCredentialsSecretArn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:app-credentials"
+
+func testPostgresRoleCredentialsConfig(trigger string) map[string]interface{} {
+	return map[string]interface{}{
+		"role":                   "app",
+		"credentials_secret_arn": testCredentialsSecretArn,
+		"host":                   "cluster.example.com",
+		"dbname":                 "app",
+		"rotation_trigger":       trigger,
+		"resource_arn":           testResourceArn,
+		"secret_arn":             testSecretArn,
+	}
+}
+
+// testRoleCredentials decodes the RDS credentials JSON of a secret.
+func testRoleCredentials(t *testing.T, secret *testSecret) map[string]interface{} {
+	t.Helper()
+
+	var credentials map[string]interface{}
+	if err := json.Unmarshal([]byte(secret.value), &credentials); err != nil {
+		t.Fatalf("secret value is not JSON: %s", err)
+	}
+	return credentials
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCredentialsCreate(t *testing.T) {
+	secret := &testSecret{}
+	conn, stop := testSecretsManager(t, map[string]*testSecret{testCredentialsSecretArn: secret})
+	defer stop()
+	executor := (&fakeExecutor{}).on("FROM pg_roles", testRecord(testLongField(1)))
+	client := &AWSClient{executor: executor, secretsmanagerconn: conn}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRoleCredentials().Schema, testPostgresRoleCredentialsConfig(""))
+	if err := resourceAwsRdsdataservicePostgresRoleCredentialsCreate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	credentials := testRoleCredentials(t, secret)
+	password, _ := credentials["password"].(string)
+	expected := map[string]interface{}{
+		"engine":   "postgres",
+		"username": "app",
+		"password": password,
+		"host":     "cluster.example.com",
+		"port":     float64(5432),
+		"dbname":   "app",
+	}
+	if fmt.Sprint(credentials) != fmt.Sprint(expected) {
+		t.Errorf("got credentials %v", credentials)
+	}
+	if len(password) != 32 {
+		t.Errorf("expected a 32 character password, got %d", len(password))
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		fmt.Sprintf(`ALTER ROLE "app" WITH ENCRYPTED PASSWORD '%s'`, strings.Replace(password, "'", "''", -1)),
+		"COMMIT",
+		"SELECT 1 FROM pg_roles WHERE rolname = :name",
+	)
+	if secret.version != testSecretVersion("put-1") || secret.pendingVersion != "" {
+		t.Errorf("expected put-1 to be the only current version, got current %q, pending %q", secret.version, secret.pendingVersion)
+	}
+	if v := d.Get("secret_version_id").(string); v != testSecretVersion("put-1") {
+		t.Errorf("got secret_version_id %q", v)
+	}
+	if d.Id() != "app|"+testCredentialsSecretArn {
+		t.Errorf("unexpected ID: %s", d.Id())
+	}
+	for attribute, value := range d.State().Attributes {
+		if strings.Contains(value, password) {
+			t.Errorf("state attribute %s holds the password", attribute)
+		}
+	}
+	if strings.Contains(logs.String(), password) {
+		t.Errorf("the password was logged:\n%s", logs.String())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCredentialsRotate(t *testing.T) {
+	secret := &testSecret{value: `{"password":"old"}`, version: testSecretVersion("old")}
+	conn, stop := testSecretsManager(t, map[string]*testSecret{testCredentialsSecretArn: secret})
+	defer stop()
+	executor := (&fakeExecutor{}).on("FROM pg_roles", testRecord(testLongField(1)))
+	client := &AWSClient{executor: executor, secretsmanagerconn: conn}
+
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresRoleCredentials(), "app|"+testCredentialsSecretArn,
+		testPostgresRoleCredentialsConfig("1"),
+		testPostgresRoleCredentialsConfig("2"),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresRoleCredentialsUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	password := testRoleCredentials(t, secret)["password"].(string)
+	if password == "old" || secret.version != testSecretVersion("put-1") {
+		t.Fatalf("expected a new current version, got %q", secret.version)
+	}
+	if !strings.Contains(aws.StringValue(executor.inputs[0].Sql), "ENCRYPTED PASSWORD") {
+		t.Errorf("expected the password to be set, got %q", aws.StringValue(executor.inputs[0].Sql))
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCredentialsRotateFailure(t *testing.T) {
+	secret := &testSecret{value: `{"password":"old"}`, version: testSecretVersion("old")}
+	conn, stop := testSecretsManager(t, map[string]*testSecret{testCredentialsSecretArn: secret})
+	defer stop()
+	executor := (&fakeExecutor{}).failOn("ALTER ROLE", fmt.Errorf("permission denied"))
+	client := &AWSClient{executor: executor, secretsmanagerconn: conn}
+
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresRoleCredentials(), "app|"+testCredentialsSecretArn,
+		testPostgresRoleCredentialsConfig("1"),
+		testPostgresRoleCredentialsConfig("2"),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresRoleCredentialsUpdate(d, client); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// Clients must keep reading the password the role still has
+	if secret.version != testSecretVersion("old") || secret.value != `{"password":"old"}` {
+		t.Errorf("expected the old version to stay current, got %q", secret.version)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCredentialsRead(t *testing.T) {
+	testCases := []struct {
+		name       string
+		roleExists bool
+		secrets    map[string]*testSecret
+		wantID     bool
+	}{
+		{
+			name:       "found",
+			roleExists: true,
+			secrets:    map[string]*testSecret{testCredentialsSecretArn: {version: "v1"}},
+			wantID:     true,
+		},
+		{
+			name:       "role dropped",
+			roleExists: false,
+			secrets:    map[string]*testSecret{testCredentialsSecretArn: {version: "v1"}},
+		},
+		{
+			name:       "secret deleted",
+			roleExists: true,
+			secrets:    map[string]*testSecret{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			conn, stop := testSecretsManager(t, testCase.secrets)
+			defer stop()
+			executor := &fakeExecutor{}
+			if testCase.roleExists {
+				executor.on("FROM pg_roles", testRecord(testLongField(1)))
+			}
+			client := &AWSClient{executor: executor, secretsmanagerconn: conn}
+
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRoleCredentials().Schema, testPostgresRoleCredentialsConfig(""))
+			d.SetId("app|" + testCredentialsSecretArn)
+
+			if err := resourceAwsRdsdataservicePostgresRoleCredentialsRead(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if (d.Id() != "") != testCase.wantID {
+				t.Errorf("got ID %q", d.Id())
+			}
+			if testCase.wantID && d.Get("secret_version_id").(string) != "v1" {
+				t.Errorf("got secret_version_id %q", d.Get("secret_version_id").(string))
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCredentialsDelete(t *testing.T) {
+	executor := (&fakeExecutor{}).on("FROM pg_roles", testRecord(testLongField(1)))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRoleCredentials().Schema, testPostgresRoleCredentialsConfig(""))
+	d.SetId("app|" + testCredentialsSecretArn)
+
+	if err := resourceAwsRdsdataservicePostgresRoleCredentialsDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"SELECT 1 FROM pg_roles WHERE rolname = :name",
+		`ALTER ROLE "app" WITH PASSWORD NULL`,
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCredentialsPostgresBackend(t *testing.T) {
+	client := &AWSClient{backend: backendPostgres, executor: &fakeExecutor{}}
+
+	_, err := resourceAwsRdsdataservicePostgresRoleCredentials().Diff(nil, terraform.NewResourceConfigRaw(testPostgresRoleCredentialsConfig("")), client)
+	if err == nil || !strings.Contains(err.Error(), "Secrets Manager is not available") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		password, err := generatePassword(24)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if len(password) != 24 {
+			t.Fatalf("expected 24 characters, got %q", password)
+		}
+		if strings.ContainsAny(password, `/@" '\`) {
+			t.Fatalf("password %q contains a character RDS does not allow", password)
+		}
+		if seen[password] {
+			t.Fatalf("password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
@@ -0,0 +1,441 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func testPostgresRoleConfig(name string, login bool) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         name,
+		"login":        login,
+		"resource_arn": testResourceArn,
+		"secret_arn":   testSecretArn,
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCreate(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresRoleConfig("app", true)
+	config["password"] = "secret"
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, config)
+
+	if err := resourceAwsRdsdataservicePostgresRoleCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`CREATE ROLE "app" WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE INHERIT NOREPLICATION NOBYPASSRLS CONNECTION LIMIT -1 ENCRYPTED PASSWORD 'secret'`,
+		"COMMIT",
+	)
+	for _, input := range executor.inputs {
+		if v := aws.StringValue(input.TransactionId); v != "tx-1" {
+			t.Fatalf("statement %q ran outside the transaction (ID %q)", aws.StringValue(input.Sql), v)
+		}
+	}
+	if d.Id() != "app" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCreateRollback(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		failOn("GRANT", fmt.Errorf("role \"admin\" does not exist"))
+	config := testPostgresRoleConfig("app", true)
+	config["rolename"] = "admin"
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, config)
+
+	if err := resourceAwsRdsdataservicePostgresRoleCreate(d, &AWSClient{executor: executor}); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`CREATE ROLE "app" WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE INHERIT NOREPLICATION NOBYPASSRLS CONNECTION LIMIT -1`,
+		`GRANT "app" to "admin";`,
+		"ROLLBACK",
+	)
+	if d.Id() != "" {
+		t.Fatalf("expected no ID, got %s", d.Id())
+	}
+}
+
+var testRoleColumns = []string{
+	"rolname", "rolsuper", "rolinherit", "rolcreaterole", "rolcreatedb", "rolcanlogin",
+	"rolreplication", "rolbypassrls", "rolconnlimit", "rolvaliduntil",
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCreateAttributes(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresRoleConfig("app", false)
+	config["inherit"] = false
+	config["create_database"] = true
+	config["create_role"] = true
+	config["replication"] = true
+	config["bypass_row_level_security"] = true
+	config["connection_limit"] = 10
+	config["valid_until"] = "2030-01-01T00:00:00Z"
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, config)
+
+	if err := resourceAwsRdsdataservicePostgresRoleCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// create_database must not overwrite create_role, and inherit = false
+	// must be spelled out as it is not the PostgreSQL default.
+	executor.expectStatements(t,
+		"BEGIN",
+		`CREATE ROLE "app" WITH NOLOGIN NOSUPERUSER CREATEDB CREATEROLE NOINHERIT REPLICATION BYPASSRLS CONNECTION LIMIT 10 VALID UNTIL '2030-01-01T00:00:00Z'`,
+		"COMMIT",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleRead(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_catalog.pg_roles", testRoleColumns, testRecord(
+			testStringField("app"),
+			testBoolField(true),
+			testBoolField(true),
+			testBoolField(false),
+			testBoolField(true),
+			testBoolField(false),
+			testBoolField(true),
+			testBoolField(false),
+			testLongField(5),
+			testStringField("2030-01-01T00:00:00Z"),
+		))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, testPostgresRoleConfig("app", true))
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresRoleRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The role is read first, then its settings and those of each database
+	if len(executor.inputs) != 3 || !strings.Contains(aws.StringValue(executor.inputs[0].Sql), "FROM pg_catalog.pg_roles WHERE rolname = :name") {
+		t.Fatalf("unexpected statements: %s", executor.inputs)
+	}
+	executor.expectParameters(t, 0, map[string]string{"name": "app"})
+	if d.Id() != "app" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+
+	// The configuration has login = true and superuser = false; the
+	// catalog disagrees, which must show up as drift.
+	expected := map[string]bool{
+		"superuser":                 true,
+		"inherit":                   true,
+		"create_role":               false,
+		"create_database":           true,
+		"login":                     false,
+		"replication":               true,
+		"bypass_row_level_security": false,
+	}
+	for attribute, want := range expected {
+		if got := d.Get(attribute).(bool); got != want {
+			t.Errorf("%s: got %t, expected %t", attribute, got, want)
+		}
+	}
+	if got := d.Get("connection_limit").(int); got != 5 {
+		t.Errorf("connection_limit: got %d, expected 5", got)
+	}
+	if got := d.Get("valid_until").(string); got != "2030-01-01T00:00:00Z" {
+		t.Errorf("valid_until: got %q", got)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleUpdate(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresRole(), "app",
+		testPostgresRoleConfig("app", false),
+		testPostgresRoleConfig("app2", true),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresRoleUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER ROLE "app" RENAME TO "app2"`,
+		`ALTER ROLE "app2" WITH LOGIN`,
+		"COMMIT",
+	)
+	if d.Id() != "app2" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleUpdateAttributes(t *testing.T) {
+	old := testPostgresRoleConfig("app", true)
+	old["password"] = "old"
+
+	testCases := []struct {
+		name     string
+		change   map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "every attribute",
+			change: map[string]interface{}{
+				"superuser":                 true,
+				"create_database":           true,
+				"create_role":               true,
+				"inherit":                   false,
+				"replication":               true,
+				"bypass_row_level_security": true,
+				"connection_limit":          3,
+				"valid_until":               "2030-01-01T00:00:00Z",
+				"password":                  "new",
+			},
+			expected: []string{
+				`ALTER ROLE "app" WITH SUPERUSER CREATEDB CREATEROLE NOINHERIT REPLICATION BYPASSRLS CONNECTION LIMIT 3 VALID UNTIL '2030-01-01T00:00:00Z' ENCRYPTED PASSWORD 'new'`,
+			},
+		},
+		{
+			name:     "only what changed",
+			change:   map[string]interface{}{"create_role": true},
+			expected: []string{`ALTER ROLE "app" WITH CREATEROLE`},
+		},
+		{
+			name:     "password removed",
+			change:   map[string]interface{}{"password": ""},
+			expected: []string{`ALTER ROLE "app" WITH PASSWORD NULL`},
+		},
+		{
+			name:   "renamed",
+			change: map[string]interface{}{"name": "app2"},
+			expected: []string{
+				`ALTER ROLE "app" RENAME TO "app2"`,
+				`ALTER ROLE "app2" WITH ENCRYPTED PASSWORD 'old'`,
+			},
+		},
+		{
+			name:     "nothing changed",
+			change:   map[string]interface{}{},
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			client := &AWSClient{executor: executor}
+
+			new := make(map[string]interface{})
+			for k, v := range old {
+				new[k] = v
+			}
+			for k, v := range testCase.change {
+				new[k] = v
+			}
+			d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresRole(), "app", old, new, client)
+
+			if err := resourceAwsRdsdataservicePostgresRoleUpdate(d, client); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			expected := append([]string{"BEGIN"}, testCase.expected...)
+			executor.expectStatements(t, append(expected, "COMMIT")...)
+		})
+	}
+}
+
+func TestSuppressEquivalentRoleValidUntil(t *testing.T) {
+	testCases := []struct {
+		old, new string
+		want     bool
+	}{
+		{"infinity", "infinity", true},
+		{"2030-01-01T00:00:00Z", "2030-01-01T02:00:00+02:00", true},
+		{"2030-01-01T00:00:00Z", "2030-01-02T00:00:00Z", false},
+		{"infinity", "2030-01-01T00:00:00Z", false},
+	}
+
+	for _, testCase := range testCases {
+		if got := suppressEquivalentRoleValidUntil("valid_until", testCase.old, testCase.new, nil); got != testCase.want {
+			t.Errorf("%q -> %q: got %t, expected %t", testCase.old, testCase.new, got, testCase.want)
+		}
+	}
+}
+
+// testRoleDeleteExecutor answers the catalog queries of a role Delete run
+// as postgres.
+func testRoleDeleteExecutor() *fakeExecutor {
+	return (&fakeExecutor{}).
+		onRows("SELECT current_user", []string{"name"}, testRecord(testStringField("postgres")))
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleDelete(t *testing.T) {
+	testCases := []struct {
+		name     string
+		member   bool
+		circular bool
+		grant    bool
+	}{
+		{
+			name:  "not a member",
+			grant: true,
+		},
+		{
+			name:   "current user is a member of the role",
+			member: true,
+		},
+		{
+			name:     "role is a member of the current user",
+			circular: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := testRoleDeleteExecutor()
+			// The membership of the current user in the role is checked
+			// first, then the reverse.
+			executor.onceOn("WITH RECURSIVE", testMembershipRecords(testCase.member)...)
+			executor.onceOn("WITH RECURSIVE", testMembershipRecords(testCase.circular)...)
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, testPostgresRoleConfig("app", true))
+			d.SetId("app")
+
+			if err := resourceAwsRdsdataservicePostgresRoleDelete(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectParameters(t, 1, map[string]string{"role": "postgres", "grant_role": "app"})
+			executor.expectParameters(t, 2, map[string]string{"role": "app", "grant_role": "postgres"})
+
+			expected := []string{"BEGIN"}
+			if testCase.grant {
+				expected = append(expected, `GRANT "app" TO CURRENT_USER;`)
+			}
+			expected = append(expected,
+				`REASSIGN OWNED BY "app" TO CURRENT_USER;`,
+				`DROP OWNED BY "app";`,
+				`DROP ROLE "app"`,
+				"COMMIT",
+			)
+			// Skip the current user and membership queries.
+			executor.statements = executor.statements[3:]
+			executor.expectStatements(t, expected...)
+		})
+	}
+}
+
+func testMembershipRecords(member bool) [][]*rdsdataservice.Field {
+	if !member {
+		return nil
+	}
+	return [][]*rdsdataservice.Field{testRecord(testLongField(1))}
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleDeleteContinuesAfterTimeout(t *testing.T) {
+	executor := testRoleDeleteExecutor().
+		failOnceOn("REASSIGN OWNED", awserr.New(rdsdataservice.ErrCodeStatementTimeoutException, "Request timed out", nil))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, testPostgresRoleConfig("app", true))
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresRoleDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The SELECT 1 in the same transaction waits for REASSIGN OWNED to
+	// finish before the role is dropped. The current user and membership
+	// queries come before the transaction.
+	executor.statements = executor.statements[3:]
+	executor.expectStatements(t,
+		"BEGIN",
+		`GRANT "app" TO CURRENT_USER;`,
+		`REASSIGN OWNED BY "app" TO CURRENT_USER;`,
+		"SELECT 1",
+		`DROP OWNED BY "app";`,
+		`DROP ROLE "app"`,
+		"COMMIT",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleSettings(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	old := testPostgresRoleConfig("app", true)
+	old["settings"] = map[string]interface{}{"pgaudit.log": "write", "idle_in_transaction_session_timeout": "60s"}
+	new := testPostgresRoleConfig("app", true)
+	new["settings"] = map[string]interface{}{"pgaudit.log": "read, write"}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresRole(), "app", old, new, client)
+
+	if err := resourceAwsRdsdataservicePostgresRoleUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER ROLE "app" RESET "idle_in_transaction_session_timeout"`,
+		`ALTER ROLE "app" SET "pgaudit"."log" = 'read, write'`,
+		"COMMIT",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleDatabaseSettings(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	old := testPostgresRoleConfig("app", true)
+	old["database_settings"] = []interface{}{
+		map[string]interface{}{"database": "app", "settings": map[string]interface{}{"search_path": "app, public"}},
+		map[string]interface{}{"database": "reporting", "settings": map[string]interface{}{"statement_timeout": "5min"}},
+	}
+	new := testPostgresRoleConfig("app", true)
+	new["database_settings"] = []interface{}{
+		map[string]interface{}{"database": "app", "settings": map[string]interface{}{"search_path": "app"}},
+	}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresRole(), "app", old, new, client)
+
+	if err := resourceAwsRdsdataservicePostgresRoleUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER ROLE "app" IN DATABASE "app" SET "search_path" = 'app'`,
+		`ALTER ROLE "app" IN DATABASE "reporting" RESET "statement_timeout"`,
+		"COMMIT",
+	)
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleReadDatabaseSettings(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_catalog.pg_roles", []string{"rolname"}, testRecord(testStringField("app"))).
+		onRows("JOIN pg_database", []string{"database", "setting"},
+			testRecord(testStringField("app"), testStringField(`search_path=app, "$user"`)),
+			testRecord(testStringField("reporting"), testStringField("statement_timeout=5min")))
+	config := testPostgresRoleConfig("app", true)
+	config["database_settings"] = []interface{}{
+		map[string]interface{}{"database": "app", "settings": map[string]interface{}{"search_path": `app,"$user"`}},
+	}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, config)
+	d.SetId("app")
+
+	if err := resourceAwsRdsdataservicePostgresRoleRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectParameters(t, 2, map[string]string{"role": "app"})
+
+	// The configured spelling is kept, and settings made outside Terraform
+	// in another database show up
+	got := roleDatabaseSettings(d.Get("database_settings"))
+	expected := map[string]map[string]interface{}{
+		"app":       {"search_path": `app,"$user"`},
+		"reporting": {"statement_timeout": "5min"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("got database settings %v, expected %v", got, expected)
+	}
+}
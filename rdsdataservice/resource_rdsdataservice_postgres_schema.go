@@ -3,9 +3,9 @@ package rdsdataservice
 import (
 	"fmt"
 	"log"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
@@ -17,122 +17,200 @@ func resourceAwsRdsdataservicePostgresSchema() *schema.Resource {
 		Update: resourceAwsRdsdataservicePostgresSchemaUpdate,
 		Delete: resourceAwsRdsdataservicePostgresSchemaDelete,
 		Exists: resourceAwsRdsdataservicePostgresSchemaExists,
-		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+		Importer: dataAPIImport{
+			resourceType: "rdsdataservice_postgres_schema",
+			database:     true,
+			names:        []string{"name"},
+			id: func(d *schema.ResourceData, target dataAPITarget) string {
+				return generateSchemaID(d, target.Database)
+			},
+		}.importer(),
+		CustomizeDiff: customizeDiffDataAPITarget(true),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
 			"name": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Schema name.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "Schema name.",
 			},
 			"resource_arn": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "DB ARN.",
+				Optional:    true,
+				Description: "The ARN of the Aurora Serverless DB cluster. Defaults to the provider resource_arn.",
 			},
 			"secret_arn": {
 				Type:        schema.TypeString,
-				Required:    true,
-				Description: "DBA Secret ARN.",
+				Optional:    true,
+				Description: "The ARN of the secret holding the cluster credentials. Defaults to the provider secret_arn.",
 			},
 			"database": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Schema name.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "The database to create the schema in. Defaults to the provider database.",
 			},
 			"owner": {
-				Type:        schema.TypeString,
-				Required:    true,
-				Description: "Schema Owner.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateNoNUL,
+				Description:  "Schema Owner.",
+			},
+			"if_not_exists": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to adopt the schema if it already exists, e.g. public, instead of failing.",
+			},
+			"drop_cascade": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Whether to also drop the objects in the schema when it is dropped.",
+			},
+			"adopted": {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the schema already existed and was adopted with if_not_exists, in which case it is not dropped on destroy.",
 			},
 		},
 	}
 }
 
 func resourceAwsRdsdataservicePostgresSchemaCreate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
 
-	sql := fmt.Sprintf("CREATE SCHEMA %s AUTHORIZATION %s;",
+	ifNotExists := ""
+	adopted := false
+	if d.Get("if_not_exists").(bool) {
+		ifNotExists = "IF NOT EXISTS "
+
+		exists, err := schemaExists(d.Get("name").(string), target, meta)
+		if err != nil {
+			return fmt.Errorf("Error creating Postgres Schema: %#v", err)
+		}
+		adopted = exists
+	}
+
+	sql := pgsql.Format("CREATE SCHEMA %s%I AUTHORIZATION %I;",
+		ifNotExists,
 		d.Get("name").(string),
 		d.Get("owner").(string))
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-		Database:	 aws.String(d.Get("database").(string)),
-	}
+	createOpts := target.statement(sql)
 
 	log.Printf("[DEBUG] Create Postgres Schema: %#v", createOpts)
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutCreate), func() (bool, error) {
+		return schemaExists(d.Get("name").(string), target, meta)
+	})
 
 	if err != nil {
 		return fmt.Errorf("Error creating Postgres Schema: %#v", err)
 	}
 
-	d.SetId(d.Get("name").(string))
+	// IF NOT EXISTS leaves the owner of an existing schema unchanged
+	if adopted {
+		if err := adoptSchema(d, target, meta); err != nil {
+			return err
+		}
+	}
+
+	d.Set("adopted", adopted)
+	d.SetId(generateSchemaID(d, target.Database))
 	log.Printf("[INFO] Postgres Schema ID: %s", d.Id())
 
 	return err
 }
 
-func resourceAwsRdsdataservicePostgresSchemaDelete(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
-
-	sql := fmt.Sprintf("DROP SCHEMA %s;",
-		d.Get("name").(string))
+func generateSchemaID(d *schema.ResourceData, database string) string {
+	return databaseObjectID(database, d.Get("name").(string))
+}
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-		Database:	 aws.String(d.Get("database").(string)),
+// adoptSchema makes owner the owner of an existing schema created with
+// if_not_exists. Adopted schemas are not dropped on destroy, as they were
+// not created by Terraform.
+func adoptSchema(d *schema.ResourceData, target dataAPITarget, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	name := d.Get("name").(string)
+	owner := d.Get("owner").(string)
+
+	owned, err := schemaOwnedBy(name, owner, target, meta)
+	if err != nil || owned {
+		return err
 	}
 
-	log.Printf("[DEBUG] Drop Postgres SCHEMA: %#v", createOpts)
+	alterOpts := target.statement(pgsql.Format("ALTER SCHEMA %I OWNER TO %I", name, owner))
+
+	log.Printf("[DEBUG] Adopt Postgres Schema: %#v", alterOpts)
 
-	_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	err = executeDDL(executor, &alterOpts, d.Timeout(schema.TimeoutCreate), func() (bool, error) {
+		return schemaOwnedBy(name, owner, target, meta)
+	})
 
 	if err != nil {
-		return fmt.Errorf("Error dropping Postgres SCHEMA: %#v", err)
+		return fmt.Errorf("Error adopting Postgres Schema: %#v", err)
 	}
 
-	d.SetId("")
-	return err
+	return nil
 }
 
-func resourceAwsRdsdataservicePostgresSchemaExists(d *schema.ResourceData, meta interface{}) (bool, error) {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+func resourceAwsRdsdataservicePostgresSchemaDelete(d *schema.ResourceData, meta interface{}) error {
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
 
-	sql := fmt.Sprintf("SELECT schema_name FROM information_schema.schemata where schema_name='%s';",
-		d.Get("name").(string))
+	if d.Get("adopted").(bool) {
+		log.Printf("[INFO] Postgres Schema %s was adopted, removing it from state without dropping it", d.Get("name").(string))
+		d.SetId("")
+		return nil
+	}
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-		Database:	 aws.String(d.Get("database").(string)),
+	cascade := ""
+	if d.Get("drop_cascade").(bool) {
+		cascade = " CASCADE"
 	}
 
-	log.Printf("[DEBUG] Check Postgres Schema exists: %#v", createOpts)
+	sql := pgsql.Format("DROP SCHEMA %I%s;",
+		d.Get("name").(string),
+		cascade)
+
+	createOpts := target.statement(sql)
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	log.Printf("[DEBUG] Drop Postgres SCHEMA: %#v", createOpts)
+
+	err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutDelete), func() (bool, error) {
+		exists, err := schemaExists(d.Get("name").(string), target, meta)
+		return !exists, err
+	})
 
 	if err != nil {
-		return false, fmt.Errorf("Error checking Postgres Schema exists: %#v", err)
+		return fmt.Errorf("Error dropping Postgres SCHEMA: %#v", err)
 	}
 
-	if len(output.Records) != 1 {
-		return false, nil
+	d.SetId("")
+	return err
+}
+
+func resourceAwsRdsdataservicePostgresSchemaExists(d *schema.ResourceData, meta interface{}) (bool, error) {
+	exists, err := schemaExists(d.Get("name").(string), resourceTarget(d, meta).withDatabase(d.Get("database").(string)), meta)
+	if err != nil {
+		return false, fmt.Errorf("Error checking Postgres Schema exists: %#v", err)
 	}
 
-	return true, nil
+	return exists, nil
 }
+
 func resourceAwsRdsdataservicePostgresSchemaUpdate(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
+	executor := meta.(*AWSClient).executor
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
 
 	if d.HasChange("name") {
 		oraw, nraw := d.GetChange("name")
@@ -142,45 +220,37 @@ func resourceAwsRdsdataservicePostgresSchemaUpdate(d *schema.ResourceData, meta
 			return fmt.Errorf("Error setting Schema name to an empty string")
 		}
 
-		sql := fmt.Sprintf("ALTER SCHEMA %s RENAME TO %s", o, n)
+		sql := pgsql.Format("ALTER SCHEMA %I RENAME TO %I", o, n)
 
-		createOpts := rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
-			Database:	 aws.String(d.Get("database").(string)),
-		}
+		createOpts := target.statement(sql)
 
 		log.Printf("[DEBUG] Update Postgres Schema name: %#v", createOpts)
 
-		_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			return schemaExists(n, target, meta)
+		})
 
 		if err != nil {
 			return fmt.Errorf("Error updating Postgres Schema name: %#v", err)
 		}
-		d.SetId(n)
+		d.SetId(generateSchemaID(d, target.Database))
 	}
 
 	if d.HasChange("owner") {
-		oraw, nraw := d.GetChange("owner")
-		o := oraw.(string)
-		n := nraw.(string)
+		n := d.Get("owner").(string)
 		if n == "" {
 			return fmt.Errorf("Error setting Schema owner to an empty string")
 		}
 
-		sql := fmt.Sprintf("ALTER SCHEMA %s OWNER TO %s", o, n)
+		sql := pgsql.Format("ALTER SCHEMA %I OWNER TO %I", d.Get("name").(string), n)
 
-		createOpts := rdsdataservice.ExecuteStatementInput{
-			ResourceArn: aws.String(d.Get("resource_arn").(string)),
-			SecretArn:   aws.String(d.Get("secret_arn").(string)),
-			Sql:         aws.String(sql),
-			Database:	 aws.String(d.Get("database").(string)),
-		}
+		createOpts := target.statement(sql)
 
 		log.Printf("[DEBUG] Update Postgres Schema owner: %#v", createOpts)
 
-		_, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+		err := executeDDL(executor, &createOpts, d.Timeout(schema.TimeoutUpdate), func() (bool, error) {
+			return schemaOwnedBy(d.Get("name").(string), n, target, meta)
+		})
 
 		if err != nil {
 			return fmt.Errorf("Error updating Postgres Schema owner: %#v", err)
@@ -189,35 +259,30 @@ func resourceAwsRdsdataservicePostgresSchemaUpdate(d *schema.ResourceData, meta
 
 	return nil
 }
-func resourceAwsRdsdataservicePostgresSchemaRead(d *schema.ResourceData, meta interface{}) error {
-	rdsdataserviceconn := meta.(*AWSClient).rdsdataserviceconn
-
-	sql := fmt.Sprintf("SELECT schema_name FROM information_schema.schemata where schema_name='%s';",
-		d.Get("name").(string))
 
-	createOpts := rdsdataservice.ExecuteStatementInput{
-		ResourceArn: aws.String(d.Get("resource_arn").(string)),
-		SecretArn:   aws.String(d.Get("secret_arn").(string)),
-		Sql:         aws.String(sql),
-		Database:	 aws.String(d.Get("database").(string)),
-	}
-
-	log.Printf("[DEBUG] Read Postgres Schema: %#v", createOpts)
+func resourceAwsRdsdataservicePostgresSchemaRead(d *schema.ResourceData, meta interface{}) error {
+	target := resourceTarget(d, meta).withDatabase(d.Get("database").(string))
 
-	output, err := rdsdataserviceconn.ExecuteStatement(&createOpts)
+	rows, err := queryRows(meta, target,
+		"SELECT pg_namespace.nspname AS schema_name, pg_roles.rolname AS schema_owner "+
+			"FROM pg_namespace JOIN pg_roles ON pg_roles.oid = pg_namespace.nspowner WHERE pg_namespace.nspname = :name;",
+		stringParameter("name", d.Get("name").(string)))
 
 	if err != nil {
 		return fmt.Errorf("Error reading Postgres Schema: %#v", err)
 	}
 
-	if len(output.Records) != 1 {
+	if len(rows) != 1 {
 		d.SetId("")
 		return nil
 	}
 
-	log.Printf("[DEBUG] Read Postgres Schema details: %#v", output)
-	d.Set("name", output.Records[0][0].StringValue)
-	d.Set("owner", output.Records[0][1].StringValue)
+	log.Printf("[DEBUG] Read Postgres Schema details: %#v", rows[0])
+	d.Set("name", rows[0].getString("schema_name"))
+	d.Set("owner", rows[0].getString("schema_owner"))
 
-	return err
-}
\ No newline at end of file
+	// Schemas created before IDs included the database are migrated here
+	d.SetId(generateSchemaID(d, target.Database))
+
+	return nil
+}
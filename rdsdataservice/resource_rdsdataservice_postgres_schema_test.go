@@ -0,0 +1,225 @@
+package rdsdataservice
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func testPostgresSchemaConfig(name, owner string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":         name,
+		"owner":        owner,
+		"database":     "app",
+		"resource_arn": testResourceArn,
+		"secret_arn":   testSecretArn,
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaCreate(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, testPostgresSchemaConfig("reporting", "app_owner"))
+
+	if err := resourceAwsRdsdataservicePostgresSchemaCreate(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `CREATE SCHEMA "reporting" AUTHORIZATION "app_owner";`)
+	if v := *executor.inputs[0].Database; v != "app" {
+		t.Fatalf("statement ran in database %q", v)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaRead(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_namespace", []string{"schema_name", "schema_owner"},
+			testRecord(testStringField("reporting"), testStringField("new_owner")))
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, testPostgresSchemaConfig("reporting", "app_owner"))
+	// IDs without the database are migrated on read
+	d.SetId("reporting")
+
+	if err := resourceAwsRdsdataservicePostgresSchemaRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if d.Id() != "app.reporting" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+	if v := d.Get("owner").(string); v != "new_owner" {
+		t.Fatalf("unexpected owner: %s", v)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaReadNotFound(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, testPostgresSchemaConfig("reporting", "app_owner"))
+	d.SetId("reporting")
+
+	if err := resourceAwsRdsdataservicePostgresSchemaRead(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, "SELECT pg_namespace.nspname AS schema_name, pg_roles.rolname AS schema_owner "+
+		"FROM pg_namespace JOIN pg_roles ON pg_roles.oid = pg_namespace.nspowner WHERE pg_namespace.nspname = :name;")
+	executor.expectParameters(t, 0, map[string]string{"name": "reporting"})
+	if v := *executor.inputs[0].Database; v != "app" {
+		t.Fatalf("query ran in database %q", v)
+	}
+	if d.Id() != "" {
+		t.Fatalf("expected resource to be removed from state, got ID %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaUpdate(t *testing.T) {
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor}
+	d := testResourceDataUpdate(t, resourceAwsRdsdataservicePostgresSchema(), "reporting",
+		testPostgresSchemaConfig("reporting", "app_owner"),
+		testPostgresSchemaConfig("analytics", "new_owner"),
+		client)
+
+	if err := resourceAwsRdsdataservicePostgresSchemaUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		`ALTER SCHEMA "reporting" RENAME TO "analytics"`,
+		`ALTER SCHEMA "analytics" OWNER TO "new_owner"`,
+	)
+	if d.Id() != "app.analytics" {
+		t.Fatalf("unexpected ID: %s", d.Id())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaDatabaseDiff(t *testing.T) {
+	r := resourceAwsRdsdataservicePostgresSchema()
+	state := schema.TestResourceDataRaw(t, r.Schema, testPostgresSchemaConfig("reporting", "app_owner"))
+	state.SetId("app.reporting")
+	config := testPostgresSchemaConfig("reporting", "app_owner")
+	config["database"] = "analytics"
+
+	diff, err := r.Diff(state.State(), terraform.NewResourceConfigRaw(config), &AWSClient{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The schema in the old database would otherwise be left behind
+	if diff == nil || !diff.RequiresNew() {
+		t.Fatal("expected changing the database to replace the schema")
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaDelete(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, testPostgresSchemaConfig("reporting", "app_owner"))
+	d.SetId("reporting")
+
+	if err := resourceAwsRdsdataservicePostgresSchemaDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `DROP SCHEMA "reporting";`)
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaDeleteCascade(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresSchemaConfig("reporting", "app_owner")
+	config["drop_cascade"] = true
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, config)
+	d.SetId("app.reporting")
+
+	if err := resourceAwsRdsdataservicePostgresSchemaDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t, `DROP SCHEMA "reporting" CASCADE;`)
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaCreateIfNotExists(t *testing.T) {
+	testCases := []struct {
+		name     string
+		exists   bool
+		owned    bool
+		expected []string
+	}{
+		{
+			name: "created",
+			expected: []string{
+				"SELECT 1 FROM pg_namespace WHERE nspname = :name",
+				`CREATE SCHEMA IF NOT EXISTS "public" AUTHORIZATION "app_owner";`,
+			},
+		},
+		{
+			name:   "adopted",
+			exists: true,
+			owned:  true,
+			expected: []string{
+				"SELECT 1 FROM pg_namespace WHERE nspname = :name",
+				`CREATE SCHEMA IF NOT EXISTS "public" AUTHORIZATION "app_owner";`,
+				"SELECT 1 FROM pg_namespace WHERE nspname = :name AND pg_catalog.pg_get_userbyid(nspowner) = :owner",
+			},
+		},
+		{
+			name:   "adopted from another role",
+			exists: true,
+			expected: []string{
+				"SELECT 1 FROM pg_namespace WHERE nspname = :name",
+				`CREATE SCHEMA IF NOT EXISTS "public" AUTHORIZATION "app_owner";`,
+				"SELECT 1 FROM pg_namespace WHERE nspname = :name AND pg_catalog.pg_get_userbyid(nspowner) = :owner",
+				`ALTER SCHEMA "public" OWNER TO "app_owner"`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			if testCase.owned {
+				executor.on("pg_get_userbyid(nspowner)", testRecord(testLongField(1)))
+			} else {
+				executor.on("pg_get_userbyid(nspowner)")
+			}
+			if testCase.exists {
+				executor.on("SELECT 1 FROM pg_namespace", testRecord(testLongField(1)))
+			}
+			config := testPostgresSchemaConfig("public", "app_owner")
+			config["if_not_exists"] = true
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, config)
+
+			if err := resourceAwsRdsdataservicePostgresSchemaCreate(d, &AWSClient{executor: executor}); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			executor.expectStatements(t, testCase.expected...)
+			if testCase.exists {
+				executor.expectParameters(t, 2, map[string]string{"name": "public", "owner": "app_owner"})
+			}
+			if d.Id() != "app.public" {
+				t.Fatalf("unexpected ID: %s", d.Id())
+			}
+			if got := d.Get("adopted").(bool); got != testCase.exists {
+				t.Fatalf("got adopted %t, expected %t", got, testCase.exists)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaDeleteAdopted(t *testing.T) {
+	executor := &fakeExecutor{}
+	config := testPostgresSchemaConfig("public", "app_owner")
+	config["if_not_exists"] = true
+	config["drop_cascade"] = true
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, config)
+	d.SetId("app.public")
+	d.Set("adopted", true)
+
+	if err := resourceAwsRdsdataservicePostgresSchemaDelete(d, &AWSClient{executor: executor}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t)
+	if d.Id() != "" {
+		t.Fatalf("expected the schema to be removed from state, got ID %q", d.Id())
+	}
+}
@@ -0,0 +1,135 @@
+package rdsdataservice
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/lib/pq"
+)
+
+// resultRow is one record of a query result, keyed by column name. Values
+// are decoded from their Data API Field into bool, int64, float64, string,
+// []byte, []interface{} for arrays, or nil for SQL NULL.
+type resultRow map[string]interface{}
+
+// queryRows runs a catalog query like queryCatalog and decodes its records.
+func queryRows(meta interface{}, target dataAPITarget, sql string, parameters ...*rdsdataservice.SqlParameter) ([]resultRow, error) {
+	output, err := queryCatalog(meta, target, sql, parameters...)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecords(output)
+}
+
+// decodeRecords maps the records of output to rows using the column names
+// in its metadata, so the statement must be run with IncludeResultMetadata.
+func decodeRecords(output *rdsdataservice.ExecuteStatementOutput) ([]resultRow, error) {
+	if len(output.Records) > 0 && len(output.ColumnMetadata) == 0 {
+		return nil, fmt.Errorf("Error decoding query result: no column metadata")
+	}
+
+	rows := make([]resultRow, 0, len(output.Records))
+	for i, record := range output.Records {
+		if len(record) != len(output.ColumnMetadata) {
+			return nil, fmt.Errorf("Error decoding query result: record %d has %d fields for %d columns", i, len(record), len(output.ColumnMetadata))
+		}
+
+		row := make(resultRow, len(record))
+		for j, field := range record {
+			row[columnName(output.ColumnMetadata[j])] = decodeField(field)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func columnName(column *rdsdataservice.ColumnMetadata) string {
+	if name := aws.StringValue(column.Name); name != "" {
+		return name
+	}
+	return aws.StringValue(column.Label)
+}
+
+// decodeField returns the Go value held by field.
+func decodeField(field *rdsdataservice.Field) interface{} {
+	switch {
+	case field == nil || aws.BoolValue(field.IsNull):
+		return nil
+	case field.BooleanValue != nil:
+		return *field.BooleanValue
+	case field.LongValue != nil:
+		return *field.LongValue
+	case field.DoubleValue != nil:
+		return *field.DoubleValue
+	case field.StringValue != nil:
+		return *field.StringValue
+	case field.BlobValue != nil:
+		return field.BlobValue
+	case field.ArrayValue != nil:
+		return decodeArrayValue(field.ArrayValue)
+	}
+	return nil
+}
+
+func decodeArrayValue(array *rdsdataservice.ArrayValue) []interface{} {
+	var values []interface{}
+	for _, v := range array.BooleanValues {
+		values = append(values, aws.BoolValue(v))
+	}
+	for _, v := range array.LongValues {
+		values = append(values, aws.Int64Value(v))
+	}
+	for _, v := range array.DoubleValues {
+		values = append(values, aws.Float64Value(v))
+	}
+	for _, v := range array.StringValues {
+		values = append(values, aws.StringValue(v))
+	}
+	for _, v := range array.ArrayValues {
+		values = append(values, decodeArrayValue(v))
+	}
+	return values
+}
+
+// getString returns the string in column, or "" if it is NULL.
+func (r resultRow) getString(column string) string {
+	v, _ := r[column].(string)
+	return v
+}
+
+// getBool returns the boolean in column, or false if it is NULL.
+func (r resultRow) getBool(column string) bool {
+	v, _ := r[column].(bool)
+	return v
+}
+
+// getInt64 returns the integer in column, or 0 if it is NULL.
+func (r resultRow) getInt64(column string) int64 {
+	v, _ := r[column].(int64)
+	return v
+}
+
+// getStringList returns the text array in column. The Data API returns some
+// arrays as a typed ArrayValue and others as their PostgreSQL text form,
+// e.g. {a,b}; both are accepted.
+func (r resultRow) getStringList(column string) ([]string, error) {
+	switch v := r[column].(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, e := range v {
+			list[i] = fmt.Sprint(e)
+		}
+		return list, nil
+	case string:
+		var list pq.StringArray
+		if err := list.Scan(v); err != nil {
+			return nil, fmt.Errorf("Error decoding column %s: %s", column, err)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("Error decoding column %s: unexpected %T", column, r[column])
+}
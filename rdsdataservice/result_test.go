@@ -0,0 +1,173 @@
+package rdsdataservice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+)
+
+func TestDecodeField(t *testing.T) {
+	testCases := []struct {
+		name     string
+		field    *rdsdataservice.Field
+		expected interface{}
+	}{
+		{
+			name:  "nil",
+			field: nil,
+		},
+		{
+			name:  "null",
+			field: &rdsdataservice.Field{IsNull: aws.Bool(true)},
+		},
+		{
+			name:     "bool",
+			field:    testBoolField(false),
+			expected: false,
+		},
+		{
+			name:     "long",
+			field:    testLongField(42),
+			expected: int64(42),
+		},
+		{
+			name:     "double",
+			field:    &rdsdataservice.Field{DoubleValue: aws.Float64(1.5)},
+			expected: 1.5,
+		},
+		{
+			name:     "string",
+			field:    testStringField("app"),
+			expected: "app",
+		},
+		{
+			name:     "blob",
+			field:    &rdsdataservice.Field{BlobValue: []byte{0, 1}},
+			expected: []byte{0, 1},
+		},
+		{
+			name: "string array",
+			field: &rdsdataservice.Field{ArrayValue: &rdsdataservice.ArrayValue{
+				StringValues: aws.StringSlice([]string{"SELECT", "INSERT"}),
+			}},
+			expected: []interface{}{"SELECT", "INSERT"},
+		},
+		{
+			name: "nested array",
+			field: &rdsdataservice.Field{ArrayValue: &rdsdataservice.ArrayValue{
+				ArrayValues: []*rdsdataservice.ArrayValue{
+					{LongValues: aws.Int64Slice([]int64{1, 2})},
+					{LongValues: aws.Int64Slice([]int64{3})},
+				},
+			}},
+			expected: []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3)}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := decodeField(testCase.field); !reflect.DeepEqual(got, testCase.expected) {
+				t.Errorf("got %#v, expected %#v", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeRecords(t *testing.T) {
+	output := &rdsdataservice.ExecuteStatementOutput{
+		ColumnMetadata: []*rdsdataservice.ColumnMetadata{
+			{Name: aws.String("rolname")},
+			{Name: aws.String("rolsuper")},
+			{Label: aws.String("rolconnlimit")},
+		},
+		Records: [][]*rdsdataservice.Field{
+			testRecord(testStringField("app"), testBoolField(true), testLongField(-1)),
+			testRecord(testStringField("root"), testBoolField(false), &rdsdataservice.Field{IsNull: aws.Bool(true)}),
+		},
+	}
+
+	rows, err := decodeRecords(output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []resultRow{
+		{"rolname": "app", "rolsuper": true, "rolconnlimit": int64(-1)},
+		{"rolname": "root", "rolsuper": false, "rolconnlimit": nil},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("got %#v, expected %#v", rows, expected)
+	}
+
+	if v := rows[1].getInt64("rolconnlimit"); v != 0 {
+		t.Errorf("NULL decoded as %d", v)
+	}
+	if v := rows[0].getString("missing"); v != "" {
+		t.Errorf("missing column decoded as %q", v)
+	}
+}
+
+func TestDecodeRecordsErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output *rdsdataservice.ExecuteStatementOutput
+	}{
+		{
+			name: "no metadata",
+			output: &rdsdataservice.ExecuteStatementOutput{
+				Records: [][]*rdsdataservice.Field{testRecord(testStringField("app"))},
+			},
+		},
+		{
+			name: "short record",
+			output: &rdsdataservice.ExecuteStatementOutput{
+				ColumnMetadata: []*rdsdataservice.ColumnMetadata{{Name: aws.String("a")}, {Name: aws.String("b")}},
+				Records:        [][]*rdsdataservice.Field{testRecord(testStringField("app"))},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := decodeRecords(testCase.output); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestResultRowGetStringList(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected []string
+	}{
+		{
+			name: "null",
+		},
+		{
+			name:     "array value",
+			value:    []interface{}{"SELECT", "INSERT"},
+			expected: []string{"SELECT", "INSERT"},
+		},
+		{
+			name:     "text form",
+			value:    `{SELECT,"with space"}`,
+			expected: []string{"SELECT", "with space"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got, err := resultRow{"privileges": testCase.value}.getStringList("privileges")
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(got, testCase.expected) {
+				t.Errorf("got %#v, expected %#v", got, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package rdsdataservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// Staging labels Secrets Manager uses to rotate secrets
+const (
+	secretStageCurrent = "AWSCURRENT"
+	secretStagePending = "AWSPENDING"
+)
+
+// secretsManager returns the Secrets Manager client, which only the Data
+// API backend sets up.
+func secretsManager(meta interface{}) (*secretsmanager.SecretsManager, error) {
+	conn := meta.(*AWSClient).secretsmanagerconn
+	if conn == nil {
+		return nil, fmt.Errorf("Secrets Manager is not available with the %q backend", backendPostgres)
+	}
+	return conn, nil
+}
+
+// currentSecretVersion returns the ID of the AWSCURRENT version of a
+// secret, without reading its value.
+func currentSecretVersion(meta interface{}, secretArn string) (string, error) {
+	versionID, err := secretVersion(meta, secretArn, secretStageCurrent)
+	if err != nil {
+		return "", fmt.Errorf("Error describing secret %s: %#v", secretArn, err)
+	}
+	if versionID == "" {
+		return "", fmt.Errorf("Error describing secret %s: it has no %s version", secretArn, secretStageCurrent)
+	}
+	return versionID, nil
+}
+
+// secretVersion returns the ID of the version of a secret with the given
+// staging label, or "" if there is none. Errors are returned unwrapped.
+func secretVersion(meta interface{}, secretArn, stage string) (string, error) {
+	conn, err := secretsManager(meta)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := conn.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretArn),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for versionID, stages := range output.VersionIdsToStages {
+		for _, s := range stages {
+			if aws.StringValue(s) == stage {
+				return versionID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// secretValue returns the current value of a secret and its version ID. If
+// key is set the secret must be a JSON object and the value of that key is
+// returned. The value is never logged.
+func secretValue(meta interface{}, secretArn, key string) (string, string, error) {
+	conn, err := secretsManager(meta)
+	if err != nil {
+		return "", "", err
+	}
+
+	log.Printf("[DEBUG] Reading secret %s", secretArn)
+	output, err := conn.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretArn),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("Error reading secret %s: %#v", secretArn, err)
+	}
+	if output.SecretString == nil {
+		return "", "", fmt.Errorf("Error reading secret %s: it has no string value", secretArn)
+	}
+
+	value := aws.StringValue(output.SecretString)
+	if key == "" {
+		return value, aws.StringValue(output.VersionId), nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		// The error is not returned as it may quote the value
+		return "", "", fmt.Errorf("Error reading secret %s: the value is not a JSON object", secretArn)
+	}
+	field, ok := fields[key].(string)
+	if !ok {
+		return "", "", fmt.Errorf("Error reading secret %s: it has no string key %q", secretArn, key)
+	}
+	return field, aws.StringValue(output.VersionId), nil
+}
+
+// putSecretVersion stores value as a new version of a secret, staged as
+// AWSPENDING, and returns its version ID. Clients keep reading the current
+// version until promoteSecretVersion is called.
+func putSecretVersion(meta interface{}, secretArn, value string) (string, error) {
+	conn, err := secretsManager(meta)
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("[DEBUG] Writing a new version of secret %s", secretArn)
+	output, err := conn.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:      aws.String(secretArn),
+		SecretString:  aws.String(value),
+		VersionStages: aws.StringSlice([]string{secretStagePending}),
+	})
+	if err != nil {
+		return "", fmt.Errorf("Error writing secret %s: %#v", secretArn, err)
+	}
+	return aws.StringValue(output.VersionId), nil
+}
+
+// promoteSecretVersion makes versionID the AWSCURRENT version of a secret.
+func promoteSecretVersion(meta interface{}, secretArn, versionID string) error {
+	conn, err := secretsManager(meta)
+	if err != nil {
+		return err
+	}
+
+	current, err := secretVersion(meta, secretArn, secretStageCurrent)
+	if err != nil {
+		return fmt.Errorf("Error describing secret %s: %#v", secretArn, err)
+	}
+
+	input := &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:        aws.String(secretArn),
+		VersionStage:    aws.String(secretStageCurrent),
+		MoveToVersionId: aws.String(versionID),
+	}
+	if current != "" {
+		input.RemoveFromVersionId = aws.String(current)
+	}
+
+	log.Printf("[DEBUG] Promoting version %s of secret %s", versionID, secretArn)
+	if _, err := conn.UpdateSecretVersionStage(input); err != nil {
+		return fmt.Errorf("Error promoting version %s of secret %s: %#v", versionID, secretArn, err)
+	}
+
+	// A leftover AWSPENDING label would make a rotation Lambda think a
+	// rotation is still in progress
+	_, err = conn.UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            aws.String(secretArn),
+		VersionStage:        aws.String(secretStagePending),
+		RemoveFromVersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return fmt.Errorf("Error promoting version %s of secret %s: %#v", versionID, secretArn, err)
+	}
+	return nil
+}
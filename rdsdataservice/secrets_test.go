@@ -0,0 +1,306 @@
+package rdsdataservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+const testPasswordSecretArn = "arn:aws:secretsmanager:us-east-1:123456789012:secret:app-password"
+
+type testSecret struct {
+	value   string
+	version string
+
+	// The AWSPENDING version written by PutSecretValue, if any
+	pendingValue   string
+	pendingVersion string
+	puts           int
+}
+
+// testSecretVersion pads name to the 32 characters of a version ID.
+func testSecretVersion(name string) string {
+	return name + strings.Repeat("0", 32-len(name))
+}
+
+// testSecretsManager serves the Secrets Manager calls the provider makes
+// for secrets, keyed by ARN. The returned function stops the server.
+func testSecretsManager(t *testing.T, secrets map[string]*testSecret) (*secretsmanager.SecretsManager, func()) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct {
+			SecretId            string
+			SecretString        string
+			VersionStage        string
+			MoveToVersionId     string
+			RemoveFromVersionId string
+		}
+		json.NewDecoder(r.Body).Decode(&input)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fail := func(code, message string) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+		}
+
+		secret, ok := secrets[input.SecretId]
+		if !ok {
+			fail("ResourceNotFoundException", "Secrets Manager can't find the specified secret.")
+			return
+		}
+
+		var output interface{}
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.GetSecretValue":
+			output = map[string]interface{}{"ARN": input.SecretId, "SecretString": secret.value, "VersionId": secret.version}
+		case "secretsmanager.DescribeSecret":
+			stages := map[string][]string{"previous": {"AWSPREVIOUS"}}
+			if secret.version != "" {
+				stages[secret.version] = []string{"AWSCURRENT"}
+			}
+			if secret.pendingVersion != "" {
+				stages[secret.pendingVersion] = []string{"AWSPENDING"}
+			}
+			output = map[string]interface{}{"ARN": input.SecretId, "VersionIdsToStages": stages}
+		case "secretsmanager.PutSecretValue":
+			secret.puts++
+			secret.pendingValue = input.SecretString
+			secret.pendingVersion = testSecretVersion(fmt.Sprintf("put-%d", secret.puts))
+			output = map[string]interface{}{"ARN": input.SecretId, "VersionId": secret.pendingVersion}
+		case "secretsmanager.UpdateSecretVersionStage":
+			switch {
+			case input.VersionStage == "AWSCURRENT" && input.RemoveFromVersionId == secret.version && input.MoveToVersionId == secret.pendingVersion:
+				secret.value, secret.version = secret.pendingValue, secret.pendingVersion
+			case input.VersionStage == "AWSPENDING" && input.RemoveFromVersionId == secret.pendingVersion && input.MoveToVersionId == "":
+				secret.pendingValue, secret.pendingVersion = "", ""
+			default:
+				fail("InvalidParameterException", fmt.Sprintf("unexpected stage change %+v", input))
+				return
+			}
+			output = map[string]interface{}{"ARN": input.SecretId}
+		default:
+			t.Errorf("unexpected Secrets Manager call %q", r.Header.Get("X-Amz-Target"))
+		}
+
+		json.NewEncoder(w).Encode(output)
+	}))
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+		Endpoint:    aws.String(server.URL),
+		MaxRetries:  aws.Int(0),
+		Region:      aws.String("us-east-1"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return secretsmanager.New(sess), server.Close
+}
+
+func TestSecretValue(t *testing.T) {
+	conn, stop := testSecretsManager(t, map[string]*testSecret{
+		"plain": {value: "s3cr3t", version: "v1"},
+		"json":  {value: `{"username":"app","password":"s3cr3t"}`, version: "v2"},
+		"text":  {value: "not json s3cr3t", version: "v3"},
+	})
+	defer stop()
+	client := &AWSClient{secretsmanagerconn: conn}
+
+	testCases := []struct {
+		name        string
+		secretArn   string
+		key         string
+		wantValue   string
+		wantVersion string
+		wantErr     string
+	}{
+		{
+			name:        "whole secret",
+			secretArn:   "plain",
+			wantValue:   "s3cr3t",
+			wantVersion: "v1",
+		},
+		{
+			name:        "json key",
+			secretArn:   "json",
+			key:         "password",
+			wantValue:   "s3cr3t",
+			wantVersion: "v2",
+		},
+		{
+			name:      "missing key",
+			secretArn: "json",
+			key:       "pass",
+			wantErr:   `it has no string key "pass"`,
+		},
+		{
+			name:      "not json",
+			secretArn: "text",
+			key:       "password",
+			wantErr:   "the value is not a JSON object",
+		},
+		{
+			name:      "missing secret",
+			secretArn: "missing",
+			wantErr:   "Error reading secret missing",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			value, version, err := secretValue(client, testCase.secretArn, testCase.key)
+			if testCase.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", testCase.wantErr, err)
+				}
+				if strings.Contains(err.Error(), "s3cr3t") {
+					t.Fatalf("error quotes the secret: %s", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if value != testCase.wantValue || version != testCase.wantVersion {
+				t.Errorf("got %q (version %q), expected %q (version %q)", value, version, testCase.wantValue, testCase.wantVersion)
+			}
+		})
+	}
+}
+
+func TestSecretsManagerPostgresBackend(t *testing.T) {
+	_, _, err := secretValue(&AWSClient{backend: backendPostgres}, testPasswordSecretArn, "")
+	if err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
+
+func testPostgresRoleSecretConfig() map[string]interface{} {
+	config := testPostgresRoleConfig("app", true)
+	config["password_secret_arn"] = testPasswordSecretArn
+	config["password_secret_key"] = "password"
+	return config
+}
+
+func TestResourceAwsRdsdataservicePostgresRoleCreatePasswordSecret(t *testing.T) {
+	conn, stop := testSecretsManager(t, map[string]*testSecret{
+		testPasswordSecretArn: {value: `{"password":"s3cr3t"}`, version: "v1"},
+	})
+	defer stop()
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor, secretsmanagerconn: conn}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresRole().Schema, testPostgresRoleSecretConfig())
+	if err := resourceAwsRdsdataservicePostgresRoleCreate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`CREATE ROLE "app" WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE INHERIT NOREPLICATION NOBYPASSRLS CONNECTION LIMIT -1 ENCRYPTED PASSWORD 's3cr3t'`,
+		"COMMIT",
+	)
+	if v := d.Get("password_secret_version").(string); v != "v1" {
+		t.Errorf("got password_secret_version %q", v)
+	}
+	for attribute, value := range d.State().Attributes {
+		if strings.Contains(value, "s3cr3t") {
+			t.Errorf("state attribute %s holds the password", attribute)
+		}
+	}
+	if strings.Contains(logs.String(), "s3cr3t") {
+		t.Errorf("the password was logged:\n%s", logs.String())
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRolePasswordSecretRotated(t *testing.T) {
+	secret := &testSecret{value: `{"password":"s3cr3t"}`, version: "v1"}
+	conn, stop := testSecretsManager(t, map[string]*testSecret{testPasswordSecretArn: secret})
+	defer stop()
+	executor := &fakeExecutor{}
+	client := &AWSClient{executor: executor, secretsmanagerconn: conn}
+	r := resourceAwsRdsdataservicePostgresRole()
+
+	state := &terraform.InstanceState{
+		ID: "app",
+		Attributes: map[string]string{
+			"id":                        "app",
+			"name":                      "app",
+			"login":                     "true",
+			"inherit":                   "true",
+			"superuser":                 "false",
+			"create_database":           "false",
+			"create_role":               "false",
+			"replication":               "false",
+			"bypass_row_level_security": "false",
+			"connection_limit":          "-1",
+			"valid_until":               "infinity",
+			"resource_arn":              testResourceArn,
+			"secret_arn":                testSecretArn,
+			"password_secret_arn":       testPasswordSecretArn,
+			"password_secret_key":       "password",
+			"password_secret_version":   "v1",
+		},
+	}
+
+	diff, err := r.Diff(state, terraform.NewResourceConfigRaw(testPostgresRoleSecretConfig()), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff != nil && !diff.Empty() {
+		t.Fatalf("expected no changes while the secret is unchanged, got %#v", diff)
+	}
+
+	secret.value, secret.version = `{"password":"n3w"}`, "v2"
+	diff, err = r.Diff(state, terraform.NewResourceConfigRaw(testPostgresRoleSecretConfig()), client)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if diff == nil || diff.Attributes["password_secret_version"] == nil || !diff.Attributes["password_secret_version"].NewComputed {
+		t.Fatalf("expected password_secret_version to change, got %#v", diff)
+	}
+
+	d, err := schema.InternalMap(r.Schema).Data(state, diff)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := resourceAwsRdsdataservicePostgresRoleUpdate(d, client); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectStatements(t,
+		"BEGIN",
+		`ALTER ROLE "app" WITH ENCRYPTED PASSWORD 'n3w'`,
+		"COMMIT",
+	)
+	if v := d.Get("password_secret_version").(string); v != "v2" {
+		t.Errorf("got password_secret_version %q", v)
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresRolePasswordSecretPostgresBackend(t *testing.T) {
+	client := &AWSClient{backend: backendPostgres, executor: &fakeExecutor{}}
+
+	_, err := resourceAwsRdsdataservicePostgresRole().Diff(nil, terraform.NewResourceConfigRaw(testPostgresRoleSecretConfig()), client)
+	if err == nil || !strings.Contains(err.Error(), "Secrets Manager is not available") {
+		t.Fatalf("expected an error, got %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package rdsdataservice
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/campisiluca/terraform-provider-rdsdataservice/rdsdataservice/internal/pgsql"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// settingsListQuoted are the parameters whose values are lists of names,
+// each of which PostgreSQL quotes as an identifier when it is stored.
+var settingsListQuoted = []string{
+	"local_preload_libraries",
+	"search_path",
+	"session_preload_libraries",
+	"shared_preload_libraries",
+	"temp_tablespaces",
+}
+
+// settingsStatements returns the statements that change the configuration
+// parameters of prefix, e.g. ALTER ROLE "app", from old to new: a SET for
+// each added or changed parameter and a RESET for each removed one.
+func settingsStatements(prefix string, old, new map[string]interface{}) []string {
+	var statements []string
+
+	for _, name := range sortedSettingNames(old) {
+		if _, ok := new[name]; !ok {
+			statements = append(statements, fmt.Sprintf("%s RESET %s", prefix, settingName(name)))
+		}
+	}
+	for _, name := range sortedSettingNames(new) {
+		value := new[name].(string)
+		if v, ok := old[name]; ok && settingsEqual(name, v.(string), value) {
+			continue
+		}
+		statements = append(statements, fmt.Sprintf("%s SET %s = %s", prefix, settingName(name), settingValue(name, value)))
+	}
+
+	return statements
+}
+
+func sortedSettingNames(settings map[string]interface{}) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// settingName quotes each part of a parameter name, which extensions
+// qualify with their own name, e.g. pgaudit.log.
+func settingName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = pgsql.Ident(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// settingValue returns value as the literals of a SET. List parameters get
+// a literal per element, as a single literal would be stored as one name.
+func settingValue(name, value string) string {
+	if !stringInSlice(strings.ToLower(name), settingsListQuoted) {
+		return pgsql.Literal(value)
+	}
+
+	items := splitSettingList(value)
+	for i, item := range items {
+		items[i] = pgsql.Literal(item)
+	}
+	return strings.Join(items, ", ")
+}
+
+// splitSettingList splits the value of a list parameter, as it is written
+// in the configuration or stored by PostgreSQL, e.g. "$user", public, into
+// its unquoted elements.
+func splitSettingList(value string) []string {
+	var items []string
+	var item strings.Builder
+	quoted := false
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' && quoted && i+1 < len(value) && value[i+1] == '"':
+			item.WriteByte('"')
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			items = append(items, strings.TrimSpace(item.String()))
+			item.Reset()
+		default:
+			item.WriteByte(c)
+		}
+	}
+
+	return append(items, strings.TrimSpace(item.String()))
+}
+
+// settingsEqual reports whether a and b are the same value of the parameter
+// name, which for list parameters ignores quoting and spacing.
+func settingsEqual(name, a, b string) bool {
+	if !stringInSlice(strings.ToLower(name), settingsListQuoted) {
+		return a == b
+	}
+	return strings.Join(splitSettingList(a), ",") == strings.Join(splitSettingList(b), ",")
+}
+
+// alterSettings runs the statements of settingsStatements in the
+// transaction transactionID.
+func alterSettings(executor statementExecutor, target dataAPITarget, transactionID *string, statements []string, timeout time.Duration) error {
+	for _, sql := range statements {
+		alterOpts := target.statement(sql)
+		alterOpts.TransactionId = transactionID
+
+		log.Printf("[DEBUG] Alter settings: %#v", alterOpts)
+
+		if err := executeDDL(executor, &alterOpts, timeout, statementCompleted(executor, target, transactionID)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSettings returns the configuration parameters set for role in
+// database from pg_db_role_setting. An empty role reads the parameters of
+// database for every role, and an empty database those of role in every
+// database.
+func readSettings(role, database string, target dataAPITarget, meta interface{}) (map[string]string, error) {
+	rows, err := queryRows(meta, target, `
+SELECT unnest(setconfig) AS setting
+FROM pg_db_role_setting
+WHERE setrole = COALESCE((SELECT oid FROM pg_roles WHERE rolname = :role), 0)
+    AND setdatabase = COALESCE((SELECT oid FROM pg_database WHERE datname = :database), 0)`,
+		stringParameter("role", role),
+		stringParameter("database", database))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading settings: %#v", err)
+	}
+
+	settings := make(map[string]string, len(rows))
+	for _, row := range rows {
+		name, value, err := splitSetting(row.getString("setting"))
+		if err != nil {
+			return nil, err
+		}
+		settings[name] = value
+	}
+
+	return settings, nil
+}
+
+// splitSetting splits an element of pg_db_role_setting.setconfig, of the
+// form name=value.
+func splitSetting(setting string) (string, string, error) {
+	parts := strings.SplitN(setting, "=", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("Error reading settings: unexpected setting %q", setting)
+	}
+	return parts[0], parts[1], nil
+}
+
+// readRoleDatabaseSettings returns the configuration parameters set for
+// role in a single database, keyed by database.
+func readRoleDatabaseSettings(role string, target dataAPITarget, meta interface{}) (map[string]map[string]string, error) {
+	rows, err := queryRows(meta, target, `
+SELECT pg_database.datname AS database, unnest(pg_db_role_setting.setconfig) AS setting
+FROM pg_db_role_setting
+JOIN pg_database ON pg_database.oid = pg_db_role_setting.setdatabase
+WHERE pg_db_role_setting.setrole = (SELECT oid FROM pg_roles WHERE rolname = :role)`,
+		stringParameter("role", role))
+
+	if err != nil {
+		return nil, fmt.Errorf("Error reading settings: %#v", err)
+	}
+
+	settings := make(map[string]map[string]string)
+	for _, row := range rows {
+		name, value, err := splitSetting(row.getString("setting"))
+		if err != nil {
+			return nil, err
+		}
+		database := row.getString("database")
+		if settings[database] == nil {
+			settings[database] = make(map[string]string)
+		}
+		settings[database][name] = value
+	}
+
+	return settings, nil
+}
+
+// roleDatabaseSettings returns the database_settings blocks of a role keyed
+// by database.
+func roleDatabaseSettings(v interface{}) map[string]map[string]interface{} {
+	settings := make(map[string]map[string]interface{})
+	for _, block := range v.(*schema.Set).List() {
+		block := block.(map[string]interface{})
+		settings[block["database"].(string)] = block["settings"].(map[string]interface{})
+	}
+	return settings
+}
+
+// roleDatabaseSettingsStatements returns the statements that change the
+// settings of role in each database from old to new.
+func roleDatabaseSettingsStatements(role string, old, new map[string]map[string]interface{}) []string {
+	databases := make(map[string]interface{}, len(old)+len(new))
+	for database := range old {
+		databases[database] = nil
+	}
+	for database := range new {
+		databases[database] = nil
+	}
+
+	var statements []string
+	for _, database := range sortedSettingNames(databases) {
+		statements = append(statements, settingsStatements(pgsql.Format("ALTER ROLE %I IN DATABASE %I", role, database), old[database], new[database])...)
+	}
+	return statements
+}
+
+// roleDatabaseSettingsState returns the database_settings blocks read from
+// the cluster to be stored in state, like settingsState.
+func roleDatabaseSettingsState(configured map[string]map[string]interface{}, read map[string]map[string]string) []interface{} {
+	databases := make([]string, 0, len(read))
+	for database := range read {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+
+	state := make([]interface{}, 0, len(databases))
+	for _, database := range databases {
+		state = append(state, map[string]interface{}{
+			"database": database,
+			"settings": settingsState(configured[database], read[database]),
+		})
+	}
+	return state
+}
+
+// settingsState returns the settings read from the cluster to be stored in
+// state, keeping the configured spelling of values that are equivalent.
+func settingsState(configured map[string]interface{}, read map[string]string) map[string]string {
+	state := make(map[string]string, len(read))
+	for name, value := range read {
+		if v, ok := configured[name]; ok && settingsEqual(name, v.(string), value) {
+			value = v.(string)
+		}
+		state[name] = value
+	}
+	return state
+}
@@ -0,0 +1,70 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSettingsStatements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		old      map[string]interface{}
+		new      map[string]interface{}
+		expected []string
+	}{
+		{
+			name:     "set",
+			new:      map[string]interface{}{"statement_timeout": "30s"},
+			expected: []string{`ALTER ROLE "app" SET "statement_timeout" = '30s'`},
+		},
+		{
+			name:     "reset",
+			old:      map[string]interface{}{"statement_timeout": "30s"},
+			expected: []string{`ALTER ROLE "app" RESET "statement_timeout"`},
+		},
+		{
+			name: "unchanged list",
+			old:  map[string]interface{}{"search_path": `"$user", public`},
+			new:  map[string]interface{}{"search_path": `"$user",public`},
+		},
+		{
+			name:     "quoted list element",
+			new:      map[string]interface{}{"search_path": `"a,b", "say ""hi""", public`},
+			expected: []string{`ALTER ROLE "app" SET "search_path" = 'a,b', 'say "hi"', 'public'`},
+		},
+		{
+			name:     "literal escaping",
+			new:      map[string]interface{}{"application_name": "it's"},
+			expected: []string{`ALTER ROLE "app" SET "application_name" = 'it''s'`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := settingsStatements(`ALTER ROLE "app"`, testCase.old, testCase.new)
+			if strings.Join(got, "\n") != strings.Join(testCase.expected, "\n") {
+				t.Fatalf("got %q, expected %q", got, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestReadSettings(t *testing.T) {
+	executor := (&fakeExecutor{}).
+		onRows("FROM pg_db_role_setting", []string{"setting"},
+			testRecord(testStringField("pgaudit.log=read,write")),
+			testRecord(testStringField("application_name=a=b")))
+
+	settings, err := readSettings("app", "", dataAPITarget{}, &AWSClient{executor: executor})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	executor.expectParameters(t, 0, map[string]string{"role": "app", "database": ""})
+	if v := settings["pgaudit.log"]; v != "read,write" {
+		t.Errorf("pgaudit.log: got %q", v)
+	}
+	if v := settings["application_name"]; v != "a=b" {
+		t.Errorf("application_name: got %q", v)
+	}
+}
@@ -0,0 +1,101 @@
+package rdsdataservice
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rdsdataservice"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// dataAPITarget identifies the Aurora cluster, the Secrets Manager secret
+// holding its credentials and the database that statements run against.
+type dataAPITarget struct {
+	ResourceArn string
+	SecretArn   string
+	Database    string
+}
+
+// resourceGetter is implemented by both *schema.ResourceData and
+// *schema.ResourceDiff.
+type resourceGetter interface {
+	Get(key string) interface{}
+}
+
+// resourceTarget resolves the target of a resource. The resource's own
+// resource_arn and secret_arn override the provider defaults; the database is
+// the provider default until overridden with withDatabase.
+func resourceTarget(d resourceGetter, meta interface{}) dataAPITarget {
+	client := meta.(*AWSClient)
+
+	target := dataAPITarget{
+		ResourceArn: client.resourceArn,
+		SecretArn:   client.secretArn,
+		Database:    client.database,
+	}
+	if v := d.Get("resource_arn").(string); v != "" {
+		target.ResourceArn = v
+	}
+	if v := d.Get("secret_arn").(string); v != "" {
+		target.SecretArn = v
+	}
+
+	return target
+}
+
+// withDatabase returns a copy of the target running statements in database,
+// or the target unchanged if database is empty.
+func (t dataAPITarget) withDatabase(database string) dataAPITarget {
+	if database != "" {
+		t.Database = database
+	}
+	return t
+}
+
+// statement returns the input for running sql against the target.
+func (t dataAPITarget) statement(sql string) rdsdataservice.ExecuteStatementInput {
+	input := rdsdataservice.ExecuteStatementInput{
+		ResourceArn: aws.String(t.ResourceArn),
+		SecretArn:   aws.String(t.SecretArn),
+		Sql:         aws.String(sql),
+	}
+	if t.Database != "" {
+		input.Database = aws.String(t.Database)
+	}
+	return input
+}
+
+// customizeDiffDataAPITarget fails the plan of a resource that has no
+// cluster or secret to run statements against, neither in its own
+// configuration nor as a provider default. When requireDatabase is set the
+// same applies to the database attribute. The postgres backend needs
+// neither: its connection decides the server, credentials and default
+// database.
+func customizeDiffDataAPITarget(requireDatabase bool) schema.CustomizeDiffFunc {
+	return func(diff *schema.ResourceDiff, meta interface{}) error {
+		client := meta.(*AWSClient)
+		if !client.requiresDataAPITarget() {
+			return nil
+		}
+
+		defaults := map[string]string{
+			"resource_arn": client.resourceArn,
+			"secret_arn":   client.secretArn,
+		}
+		if requireDatabase {
+			defaults["database"] = client.database
+		}
+
+		for _, key := range []string{"resource_arn", "secret_arn", "database"} {
+			providerDefault, ok := defaults[key]
+			if !ok || !diff.NewValueKnown(key) {
+				continue
+			}
+			if diff.Get(key).(string) == "" && providerDefault == "" {
+				return fmt.Errorf("%s must be set, either on the resource or in the provider configuration", key)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package rdsdataservice
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+const (
+	testProviderResourceArn = "arn:aws:rds:us-east-1:123456789012:cluster:provider"
+	testProviderSecretArn   = "arn:aws:secretsmanager:us-east-1:123456789012:secret:provider"
+)
+
+func testProviderDefaultsClient(executor statementExecutor) *AWSClient {
+	return &AWSClient{
+		executor:    executor,
+		resourceArn: testProviderResourceArn,
+		secretArn:   testProviderSecretArn,
+		database:    "postgres",
+	}
+}
+
+func TestResourceTarget(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config map[string]interface{}
+		want   dataAPITarget
+	}{
+		{
+			name:   "provider defaults",
+			config: map[string]interface{}{"name": "app"},
+			want: dataAPITarget{
+				ResourceArn: testProviderResourceArn,
+				SecretArn:   testProviderSecretArn,
+				Database:    "postgres",
+			},
+		},
+		{
+			name:   "resource overrides",
+			config: testPostgresDatabaseConfig("app", "app_owner"),
+			want: dataAPITarget{
+				ResourceArn: testResourceArn,
+				SecretArn:   testSecretArn,
+				Database:    "postgres",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresDatabase().Schema, testCase.config)
+
+			if got := resourceTarget(d, testProviderDefaultsClient(nil)); got != testCase.want {
+				t.Errorf("got %#v, expected %#v", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestResourceAwsRdsdataservicePostgresSchemaCreateProviderDefaults(t *testing.T) {
+	executor := &fakeExecutor{}
+	d := schema.TestResourceDataRaw(t, resourceAwsRdsdataservicePostgresSchema().Schema, map[string]interface{}{
+		"name":  "reporting",
+		"owner": "app_owner",
+	})
+
+	if err := resourceAwsRdsdataservicePostgresSchemaCreate(d, testProviderDefaultsClient(executor)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	input := executor.inputs[0]
+	if v := *input.ResourceArn; v != testProviderResourceArn {
+		t.Errorf("statement used cluster %q", v)
+	}
+	if v := *input.SecretArn; v != testProviderSecretArn {
+		t.Errorf("statement used secret %q", v)
+	}
+	if v := *input.Database; v != "postgres" {
+		t.Errorf("statement ran in database %q", v)
+	}
+}
+
+func TestCustomizeDiffDataAPITarget(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resource *schema.Resource
+		config   map[string]interface{}
+		client   *AWSClient
+		wantErr  string
+	}{
+		{
+			name:     "resource values",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			config:   testPostgresSchemaConfig("reporting", "app_owner"),
+			client:   &AWSClient{},
+		},
+		{
+			name:     "provider defaults",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			config:   map[string]interface{}{"name": "reporting", "owner": "app_owner"},
+			client:   testProviderDefaultsClient(nil),
+		},
+		{
+			name:     "missing resource_arn",
+			resource: resourceAwsRdsdataservicePostgresRole(),
+			config:   map[string]interface{}{"name": "app", "secret_arn": testSecretArn},
+			client:   &AWSClient{},
+			wantErr:  "resource_arn must be set",
+		},
+		{
+			name:     "missing secret_arn",
+			resource: resourceAwsRdsdataservicePostgresDatabase(),
+			config:   map[string]interface{}{"name": "app", "resource_arn": testResourceArn},
+			client:   &AWSClient{},
+			wantErr:  "secret_arn must be set",
+		},
+		{
+			name:     "missing database",
+			resource: resourceAwsRdsdataservicePostgresGrant(),
+			config: map[string]interface{}{
+				"role":         "app",
+				"schema":       "public",
+				"object_type":  "table",
+				"privileges":   []interface{}{"SELECT"},
+				"resource_arn": testResourceArn,
+				"secret_arn":   testSecretArn,
+			},
+			client:  &AWSClient{},
+			wantErr: "database must be set",
+		},
+		{
+			name:     "database not required",
+			resource: resourceAwsRdsdataservicePostgresDatabase(),
+			config:   testPostgresDatabaseConfig("app", "app_owner"),
+			client:   &AWSClient{},
+		},
+		{
+			name:     "postgres backend",
+			resource: resourceAwsRdsdataservicePostgresSchema(),
+			config:   map[string]interface{}{"name": "reporting", "owner": "app_owner"},
+			client:   &AWSClient{backend: backendPostgres},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := testCase.resource.Diff(nil, terraform.NewResourceConfigRaw(testCase.config), testCase.client)
+
+			if testCase.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), testCase.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", testCase.wantErr, err)
+			}
+		})
+	}
+}